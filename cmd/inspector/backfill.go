@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/devlongs/mev-inspector/internal/metrics"
+)
+
+const (
+	backfillUsage = "usage: mev-inspector backfill --from N --to M [--chunk-size K] [--concurrency C]"
+
+	progressInterval = 10 * time.Second
+)
+
+// blockRange is an inclusive range of blocks
+type blockRange struct {
+	from, to uint64
+}
+
+func (r blockRange) size() uint64 {
+	return r.to - r.from + 1
+}
+
+// chunker hands out consecutive block ranges to backfill workers
+type chunker struct {
+	next uint64 // First block not handed out yet
+	end  uint64
+	size uint64
+	mu   sync.Mutex
+}
+
+func newChunker(from, to, size uint64) *chunker {
+	return &chunker{
+		next: from,
+		end:  to,
+		size: size,
+	}
+}
+
+// take returns the next range to process, or false when none are left
+func (c *chunker) take() (blockRange, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.next > c.end {
+		return blockRange{}, false
+	}
+
+	r := blockRange{from: c.next, to: c.next + c.size - 1}
+	if r.to > c.end || r.to < r.from {
+		r.to = c.end
+	}
+	c.next = r.to + 1
+
+	return r, true
+}
+
+// backfill reprocesses a historical block range with concurrent workers
+type backfill struct {
+	inspector   *Inspector
+	chunks      *chunker
+	total       uint64
+	done        atomic.Uint64
+	concurrency int
+}
+
+// runBackfill handles the backfill subcommand
+func runBackfill(args []string) {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	from := fs.Uint64("from", 0, "first block to process")
+	to := fs.Uint64("to", 0, "last block to process (inclusive)")
+	chunkSize := fs.Uint64("chunk-size", 0, "blocks per chunk (default inspector.batch_size)")
+	concurrency := fs.Int("concurrency", 4, "chunks processed in parallel")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, backfillUsage)
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if *to == 0 || *to < *from || *concurrency < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	cfg := loadConfig()
+
+	if *chunkSize == 0 {
+		*chunkSize = uint64(cfg.Inspector.BatchSize)
+	}
+	if *chunkSize == 0 {
+		*chunkSize = 1
+	}
+
+	// A backfill doesn't follow the head: leave the checkpoint of the live
+	// inspector alone and skip reorg tracking and the API buffer
+	cfg.Checkpoint.Enabled = false
+	cfg.Inspector.ReorgDepth = 0
+	cfg.API.Enabled = false
+
+	inspector, err := NewInspector(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create inspector")
+	}
+	defer inspector.Close()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if cfg.Metrics.Enabled {
+		go func() {
+			if err := metrics.Serve(ctx, cfg.Metrics.ListenAddr); err != nil {
+				log.Error().Err(err).Msg("Metrics server stopped")
+			}
+		}()
+	}
+
+	b := &backfill{
+		inspector:   inspector,
+		chunks:      newChunker(*from, *to, *chunkSize),
+		total:       *to - *from + 1,
+		concurrency: *concurrency,
+	}
+
+	if err := b.run(ctx); err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Warn().
+				Uint64("processed", b.done.Load()).
+				Uint64("total", b.total).
+				Msg("Backfill interrupted")
+			return
+		}
+		log.Fatal().Err(err).Msg("Backfill failed")
+	}
+}
+
+// run processes every chunk and returns once the whole range is done or a
+// chunk fails
+func (b *backfill) run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	log.Info().
+		Uint64("from", b.chunks.next).
+		Uint64("to", b.chunks.end).
+		Uint64("chunkSize", b.chunks.size).
+		Int("concurrency", b.concurrency).
+		Msg("Starting backfill")
+
+	start := time.Now()
+	errCh := make(chan error, b.concurrency)
+
+	var wg sync.WaitGroup
+	for w := 0; w < b.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := b.work(ctx); err != nil {
+				errCh <- err
+				cancel()
+			}
+		}()
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.logProgress(start)
+		case <-finished:
+			select {
+			case err := <-errCh:
+				return err
+			default:
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			log.Info().
+				Uint64("blocks", b.total).
+				Dur("elapsed", time.Since(start).Round(time.Second)).
+				Msg("Backfill complete")
+			return nil
+		}
+	}
+}
+
+// work processes chunks until none are left. eth_getLogs ranges the
+// provider rejects are split by the RPC client, so any error is fatal.
+func (b *backfill) work(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r, ok := b.chunks.take()
+		if !ok {
+			return nil
+		}
+
+		if err := b.inspector.processBlockRange(ctx, r.from, r.to); err != nil {
+			return fmt.Errorf("failed to process blocks %d-%d: %w", r.from, r.to, err)
+		}
+		if err := b.inspector.sinks.Flush(); err != nil {
+			return fmt.Errorf("failed to deliver blocks %d-%d: %w", r.from, r.to, err)
+		}
+		b.done.Add(r.size())
+	}
+}
+
+// logProgress reports how much of the range is done and the estimated time
+// to completion
+func (b *backfill) logProgress(start time.Time) {
+	done := b.done.Load()
+	elapsed := time.Since(start)
+	rate := float64(done) / elapsed.Seconds()
+
+	event := log.Info().
+		Uint64("processed", done).
+		Uint64("total", b.total).
+		Str("progress", fmt.Sprintf("%.1f%%", float64(done)*100/float64(b.total))).
+		Str("rate", fmt.Sprintf("%.1f blocks/s", rate))
+
+	if rate > 0 {
+		eta := time.Duration(float64(b.total-done) / rate * float64(time.Second))
+		event = event.Dur("eta", eta.Round(time.Second))
+	}
+
+	event.Msg("Backfill progress")
+}
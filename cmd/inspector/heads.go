@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+
+	"github.com/devlongs/mev-inspector/internal/metrics"
+)
+
+// Delay before resubscribing after the head subscription is lost, doubled
+// after every failed attempt
+const (
+	minResubscribeDelay = time.Second
+	maxResubscribeDelay = time.Minute
+)
+
+// A subscription that delivers no head for this many poll intervals is
+// treated as lost
+const staleHeadIntervals = 5
+
+// How long to wait for the RPC endpoints to see a block announced over the
+// subscription before processing up to what they do see
+const (
+	headSyncWait    = 2 * time.Second
+	headSyncBackoff = 250 * time.Millisecond
+)
+
+// watchHeads keeps a new heads subscription open and sends the number of each
+// new head on heads. When the subscription fails it's retried with
+// exponential backoff; the main loop polls in the meantime.
+func (i *Inspector) watchHeads(ctx context.Context, heads chan uint64) {
+	delay := minResubscribeDelay
+
+	for {
+		started := time.Now()
+		err := i.followHeads(ctx, heads)
+		i.subscribed.Store(false)
+		metrics.HeadSubscriptionActive.Set(0)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		// A subscription that held up for a while starts the backoff over
+		if time.Since(started) > maxResubscribeDelay {
+			delay = minResubscribeDelay
+		}
+
+		metrics.HeadSubscriptionDrops.Inc()
+		log.Warn().
+			Err(err).
+			Dur("retryIn", delay).
+			Msg("New heads subscription unavailable, polling until it reconnects")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxResubscribeDelay)
+	}
+}
+
+// followHeads subscribes to new heads and forwards them until the
+// subscription fails, goes quiet or ctx is cancelled
+func (i *Inspector) followHeads(ctx context.Context, heads chan uint64) error {
+	ch := make(chan *ethtypes.Header, 16)
+	sub, err := i.client.SubscribeNewHead(ctx, ch)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	i.subscribed.Store(true)
+	metrics.HeadSubscriptionActive.Set(1)
+	log.Info().Msg("Subscribed to new heads")
+
+	staleAfter := staleHeadIntervals * i.cfg.Inspector.PollInterval
+	stale := time.NewTimer(staleAfter)
+	defer stale.Stop()
+
+	// Blocks mined while the subscription was down are picked up by the
+	// first catch-up
+	notifyHead(heads, 0)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case err := <-sub.Err():
+			if err == nil {
+				err = errors.New("subscription closed")
+			}
+			return err
+
+		case <-stale.C:
+			return fmt.Errorf("no new heads for %s", staleAfter)
+
+		case header := <-ch:
+			if !stale.Stop() {
+				<-stale.C
+			}
+			stale.Reset(staleAfter)
+			notifyHead(heads, header.Number.Uint64())
+		}
+	}
+}
+
+// notifyHead hands the latest head to the main loop without blocking. Only
+// the newest notification matters, so an unread one is replaced. heads must
+// have a buffer and this must be its only sender.
+func notifyHead(heads chan uint64, number uint64) {
+	select {
+	case <-heads:
+	default:
+	}
+	heads <- number
+}
+
+// catchUp processes batches until the last processed block reaches the chain
+// head. A head announced over the subscription can be ahead of the RPC
+// endpoints for a moment, so with target set it waits briefly for them to
+// reach it.
+func (i *Inspector) catchUp(ctx context.Context, target uint64) error {
+	deadline := time.Now().Add(headSyncWait)
+
+	for {
+		head, err := i.client.BlockNumber(ctx)
+		if err != nil {
+			return err
+		}
+
+		if head < target && time.Now().Before(deadline) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(headSyncBackoff):
+			}
+			continue
+		}
+
+		done, err := i.processNewBlocks(ctx, head)
+		if err != nil || done {
+			return err
+		}
+	}
+}
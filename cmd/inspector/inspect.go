@@ -0,0 +1,578 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+
+	"github.com/devlongs/mev-inspector/internal/arbitrage"
+	"github.com/devlongs/mev-inspector/internal/config"
+	"github.com/devlongs/mev-inspector/internal/decoder"
+	"github.com/devlongs/mev-inspector/internal/eth"
+	"github.com/devlongs/mev-inspector/internal/pricing"
+	"github.com/devlongs/mev-inspector/internal/token"
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+const inspectUsage = "usage: mev-inspector inspect tx <hash> [--json]"
+
+// txReport is the breakdown of a single inspected transaction. Token amounts
+// and wei values are decimal strings so JSON output doesn't lose precision.
+type txReport struct {
+	TxHash            string            `json:"txHash"`
+	BlockNumber       uint64            `json:"blockNumber"`
+	From              string            `json:"from"`
+	To                string            `json:"to,omitempty"`
+	Status            string            `json:"status"`
+	GasUsed           uint64            `json:"gasUsed"`
+	EffectiveGasPrice string            `json:"effectiveGasPrice"`
+	BaseFee           string            `json:"baseFee,omitempty"`
+	GasCostWei        string            `json:"gasCostWei"`
+	BurnedFeeWei      string            `json:"burnedFeeWei,omitempty"`
+	PriorityFeeWei    string            `json:"priorityFeeWei,omitempty"`
+	BlobFeeWei        string            `json:"blobFeeWei,omitempty"`
+	Swaps             []legReport       `json:"swaps"`
+	TokenFlows        []tokenFlowReport `json:"tokenFlows"`
+	Arbitrages        []arbReport       `json:"arbitrages"`
+
+	tokens *token.Registry // Renders token amounts in the text output
+}
+
+// legReport is one decoded swap, oriented by the direction tokens moved
+type legReport struct {
+	LogIndex       uint   `json:"logIndex"`
+	Protocol       string `json:"protocol"`
+	Pool           string `json:"pool"`
+	PoolID         string `json:"poolId,omitempty"`
+	Sender         string `json:"sender"`
+	Recipient      string `json:"recipient"`
+	TokenIn        string `json:"tokenIn,omitempty"`
+	TokenInSymbol  string `json:"tokenInSymbol,omitempty"`
+	AmountIn       string `json:"amountIn,omitempty"`
+	TokenOut       string `json:"tokenOut,omitempty"`
+	TokenOutSymbol string `json:"tokenOutSymbol,omitempty"`
+	AmountOut      string `json:"amountOut,omitempty"`
+}
+
+// tokenFlowReport sums what a transaction paid into and received from pools
+// for one token. Net is received minus paid.
+type tokenFlowReport struct {
+	Token    string `json:"token"`
+	Symbol   string `json:"symbol,omitempty"`
+	Paid     string `json:"paid"`
+	Received string `json:"received"`
+	Net      string `json:"net"`
+}
+
+type flashLoanReport struct {
+	Protocol string `json:"protocol"`
+	Lender   string `json:"lender"`
+	Token    string `json:"token"`
+	Symbol   string `json:"symbol,omitempty"`
+	Amount   string `json:"amount"`
+	Fee      string `json:"fee"`
+}
+
+type arbReport struct {
+	Type          string            `json:"type"`
+	Arbitrageur   string            `json:"arbitrageur"`
+	Legs          []legReport       `json:"legs"`
+	TokenStart    string            `json:"tokenStart"`
+	TokenEnd      string            `json:"tokenEnd"`
+	AmountIn      string            `json:"amountIn"`
+	AmountOut     string            `json:"amountOut"`
+	ProfitToken   string            `json:"profitToken"`
+	ProfitSymbol  string            `json:"profitSymbol,omitempty"`
+	Profit        string            `json:"profit"`
+	GasUsed       uint64            `json:"gasUsed"`
+	GasPrice      string            `json:"gasPrice,omitempty"`
+	BurnedFee     string            `json:"burnedFee,omitempty"`
+	PriorityFee   string            `json:"priorityFee,omitempty"`
+	BlobFee       string            `json:"blobFee,omitempty"`
+	FlashLoans    []flashLoanReport `json:"flashLoans,omitempty"`
+	FlashLoanFees string            `json:"flashLoanFees,omitempty"`
+	NetProfitWei  string            `json:"netProfitWei,omitempty"`
+	ProfitETHWei  string            `json:"profitEthWei,omitempty"`
+	ProfitUSD     *float64          `json:"profitUsd,omitempty"`
+}
+
+// runInspect handles the inspect subcommand
+func runInspect(args []string) {
+	if len(args) == 0 || args[0] != "tx" {
+		fmt.Fprintln(os.Stderr, inspectUsage)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("inspect tx", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "print the breakdown as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, inspectUsage)
+		fs.PrintDefaults()
+	}
+
+	// Allow flags after the hash as well as before it
+	var flags, positional []string
+	for _, arg := range args[1:] {
+		if strings.HasPrefix(arg, "-") {
+			flags = append(flags, arg)
+		} else {
+			positional = append(positional, arg)
+		}
+	}
+	_ = fs.Parse(flags)
+
+	if len(positional) != 1 || len(strings.TrimPrefix(positional[0], "0x")) != 64 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	txHash := common.HexToHash(positional[0])
+
+	cfg := loadConfig()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	report, err := inspectTx(ctx, cfg, txHash)
+	if err != nil {
+		log.Fatal().Err(err).Str("tx", txHash.Hex()).Msg("Failed to inspect transaction")
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			log.Fatal().Err(err).Msg("Failed to write report")
+		}
+		return
+	}
+
+	printReport(os.Stdout, report)
+}
+
+// inspectTx decodes the swaps of a transaction and runs arbitrage detection
+// on them
+func inspectTx(ctx context.Context, cfg *config.Config, txHash common.Hash) (*txReport, error) {
+	client, err := eth.NewClient(cfg.RPC)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	dec, err := decoder.NewDecoder(client, cfg.Inspector.Protocols)
+	if err != nil {
+		return nil, err
+	}
+	det := arbitrage.NewDetector(client)
+
+	tx, receipt, err := client.TransactionAndReceipt(ctx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+
+	logs := make([]ethtypes.Log, 0, len(receipt.Logs))
+	for _, l := range receipt.Logs {
+		logs = append(logs, *l)
+	}
+	dec.PrefetchPools(ctx, logs)
+
+	swaps, err := dec.DecodeSwapsForTransaction(ctx, logs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode swaps: %w", err)
+	}
+
+	arbs, err := det.DetectArbitrage(ctx, txHash, swaps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect arbitrage: %w", err)
+	}
+
+	if len(arbs) > 0 {
+		pricer, err := pricing.NewPricer(cfg.Pricing)
+		if err != nil {
+			return nil, err
+		}
+		book := blockBook(ctx, dec, receipt.BlockNumber.Uint64(), swaps)
+		for idx := range arbs {
+			pricer.Value(book, &arbs[idx])
+		}
+	}
+
+	// Unresolved tokens are shown by address with raw amounts
+	tokens, err := token.NewRegistry(client, cfg.Tokens.CachePath)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []common.Address
+	for _, swap := range swaps {
+		addrs = append(addrs, swap.Token0, swap.Token1)
+	}
+	for _, arb := range arbs {
+		for _, loan := range arb.FlashLoans {
+			addrs = append(addrs, loan.Token)
+		}
+	}
+	if err := tokens.Resolve(ctx, addrs); err != nil {
+		log.Warn().Err(err).Msg("Failed to resolve token metadata")
+	}
+
+	report := &txReport{
+		TxHash:      txHash.Hex(),
+		BlockNumber: receipt.BlockNumber.Uint64(),
+		Status:      "success",
+		GasUsed:     receipt.GasUsed,
+		Swaps:       newLegReports(swaps, tokens),
+		TokenFlows:  newTokenFlowReports(swaps, tokens),
+		Arbitrages:  make([]arbReport, 0, len(arbs)),
+		tokens:      tokens,
+	}
+	if receipt.Status != ethtypes.ReceiptStatusSuccessful {
+		report.Status = "reverted"
+	}
+
+	from, err := ethtypes.Sender(ethtypes.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover sender: %w", err)
+	}
+	report.From = from.Hex()
+	if tx.To() != nil {
+		report.To = tx.To().Hex()
+	}
+
+	gasPrice := receipt.EffectiveGasPrice
+	if gasPrice == nil {
+		gasPrice = tx.GasPrice()
+	}
+	report.EffectiveGasPrice = gasPrice.String()
+
+	gasCost := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(receipt.GasUsed))
+	if blobFee := arbitrage.BlobFee(tx, receipt); blobFee != nil {
+		report.BlobFeeWei = blobFee.String()
+		gasCost.Add(gasCost, blobFee)
+	}
+	report.GasCostWei = gasCost.String()
+
+	if header, err := client.HeaderByNumber(ctx, receipt.BlockNumber); err != nil {
+		log.Warn().Err(err).Msg("Failed to fetch block header, gas cost is not split")
+	} else {
+		if header.BaseFee != nil {
+			report.BaseFee = header.BaseFee.String()
+		}
+		burned, priority := arbitrage.SplitGasCost(receipt.GasUsed, gasPrice, header.BaseFee)
+		report.BurnedFeeWei = burned.String()
+		report.PriorityFeeWei = priority.String()
+	}
+
+	for idx := range arbs {
+		report.Arbitrages = append(report.Arbitrages, newArbReport(&arbs[idx], tokens))
+	}
+
+	return report, nil
+}
+
+// blockBook builds the price book of a block from all of its swaps. If the
+// block's logs can't be fetched it falls back to the swaps of the
+// transaction alone.
+func blockBook(ctx context.Context, dec *decoder.Decoder, block uint64, txSwaps []types.Swap) *pricing.Book {
+	logs, err := dec.GetAllSwapLogs(ctx, block, block)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to fetch block swaps, pricing from the transaction only")
+		return pricing.NewBook(txSwaps)
+	}
+	dec.PrefetchPools(ctx, logs)
+
+	swaps, err := dec.DecodeSwapsForTransaction(ctx, logs)
+	if err != nil {
+		return pricing.NewBook(txSwaps)
+	}
+	return pricing.NewBook(swaps)
+}
+
+func newLegReports(swaps []types.Swap, tokens *token.Registry) []legReport {
+	out := make([]legReport, 0, len(swaps))
+	for idx := range swaps {
+		swap := &swaps[idx]
+		leg := legReport{
+			LogIndex:  swap.LogIndex,
+			Protocol:  swap.Protocol,
+			Pool:      swap.Pool.Hex(),
+			Sender:    swap.Sender.Hex(),
+			Recipient: swap.Recipient.Hex(),
+		}
+		if swap.PoolID != (common.Hash{}) {
+			leg.PoolID = swap.PoolID.Hex()
+		}
+		if flow, ok := arbitrage.FlowOf(swap); ok {
+			leg.TokenIn = flow.TokenIn.Hex()
+			leg.TokenInSymbol = symbol(tokens, flow.TokenIn)
+			leg.AmountIn = flow.AmountIn.String()
+			leg.TokenOut = flow.TokenOut.Hex()
+			leg.TokenOutSymbol = symbol(tokens, flow.TokenOut)
+			leg.AmountOut = flow.AmountOut.String()
+		}
+		out = append(out, leg)
+	}
+	return out
+}
+
+// newTokenFlowReports nets what was paid into and received from pools for
+// every token, in order of first appearance
+func newTokenFlowReports(swaps []types.Swap, tokens *token.Registry) []tokenFlowReport {
+	type totals struct{ paid, received *big.Int }
+
+	var order []common.Address
+	byToken := make(map[common.Address]*totals)
+	get := func(token common.Address) *totals {
+		t, ok := byToken[token]
+		if !ok {
+			t = &totals{paid: new(big.Int), received: new(big.Int)}
+			byToken[token] = t
+			order = append(order, token)
+		}
+		return t
+	}
+
+	for idx := range swaps {
+		flow, ok := arbitrage.FlowOf(&swaps[idx])
+		if !ok {
+			continue
+		}
+		get(flow.TokenIn).paid.Add(get(flow.TokenIn).paid, flow.AmountIn)
+		get(flow.TokenOut).received.Add(get(flow.TokenOut).received, flow.AmountOut)
+	}
+
+	out := make([]tokenFlowReport, 0, len(order))
+	for _, token := range order {
+		t := byToken[token]
+		out = append(out, tokenFlowReport{
+			Token:    token.Hex(),
+			Symbol:   symbol(tokens, token),
+			Paid:     t.paid.String(),
+			Received: t.received.String(),
+			Net:      new(big.Int).Sub(t.received, t.paid).String(),
+		})
+	}
+	return out
+}
+
+func newArbReport(arb *types.Arbitrage, tokens *token.Registry) arbReport {
+	a := arbReport{
+		Type:          string(arb.Type),
+		Arbitrageur:   arb.Arbitrageur.Hex(),
+		Legs:          newLegReports(arb.Path, tokens),
+		TokenStart:    arb.TokenStart.Hex(),
+		TokenEnd:      arb.TokenEnd.Hex(),
+		AmountIn:      bigString(arb.AmountIn),
+		AmountOut:     bigString(arb.AmountOut),
+		ProfitToken:   arb.ProfitToken.Hex(),
+		ProfitSymbol:  symbol(tokens, arb.ProfitToken),
+		Profit:        bigString(arb.Profit),
+		GasUsed:       arb.GasUsed,
+		GasPrice:      bigString(arb.GasPrice),
+		BurnedFee:     bigString(arb.BurnedFee),
+		PriorityFee:   bigString(arb.PriorityFee),
+		BlobFee:       bigString(arb.BlobFee),
+		FlashLoanFees: bigString(arb.FlashLoanFees),
+		NetProfitWei:  bigString(arb.NetProfitWei),
+		ProfitETHWei:  bigString(arb.ProfitETH),
+		ProfitUSD:     arb.ProfitUSD,
+	}
+	for _, loan := range arb.FlashLoans {
+		a.FlashLoans = append(a.FlashLoans, flashLoanReport{
+			Protocol: loan.Protocol,
+			Lender:   loan.Lender.Hex(),
+			Token:    loan.Token.Hex(),
+			Symbol:   symbol(tokens, loan.Token),
+			Amount:   bigString(loan.Amount),
+			Fee:      bigString(loan.Fee),
+		})
+	}
+	return a
+}
+
+// printReport writes the human-readable breakdown of a transaction
+func printReport(w io.Writer, r *txReport) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Transaction\t%s\n", r.TxHash)
+	fmt.Fprintf(tw, "Block\t%d\n", r.BlockNumber)
+	fmt.Fprintf(tw, "From\t%s\n", r.From)
+	if r.To != "" {
+		fmt.Fprintf(tw, "To\t%s\n", r.To)
+	} else {
+		fmt.Fprintf(tw, "To\t(contract creation)\n")
+	}
+	fmt.Fprintf(tw, "Status\t%s\n", r.Status)
+	fmt.Fprintf(tw, "Gas used\t%d\n", r.GasUsed)
+	fmt.Fprintf(tw, "Gas price\t%s wei\n", r.EffectiveGasPrice)
+	if r.BaseFee != "" {
+		fmt.Fprintf(tw, "Base fee\t%s wei\n", r.BaseFee)
+	}
+	fmt.Fprintf(tw, "Gas cost\t%s ETH\n", formatEther(r.GasCostWei))
+	if r.PriorityFeeWei != "" {
+		fmt.Fprintf(tw, "  Burned\t%s ETH\n", formatEther(r.BurnedFeeWei))
+		fmt.Fprintf(tw, "  Priority fee\t%s ETH (to proposer)\n", formatEther(r.PriorityFeeWei))
+	}
+	if r.BlobFeeWei != "" {
+		fmt.Fprintf(tw, "  Blob fee\t%s ETH (burned)\n", formatEther(r.BlobFeeWei))
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\nSwaps (%d)\n", len(r.Swaps))
+	r.printLegs(w, r.Swaps)
+
+	if len(r.TokenFlows) > 0 {
+		fmt.Fprintln(w, "\nToken flows")
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "  Token\tPaid\tReceived\tNet\t")
+		for _, f := range r.TokenFlows {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t\n",
+				r.tokens.Symbol(common.HexToAddress(f.Token)), r.units(f.Token, f.Paid), r.units(f.Token, f.Received), r.units(f.Token, f.Net))
+		}
+		tw.Flush()
+	}
+
+	if len(r.Arbitrages) == 0 {
+		fmt.Fprintln(w, "\nNo arbitrage detected")
+		return
+	}
+
+	for idx, arb := range r.Arbitrages {
+		fmt.Fprintf(w, "\nArbitrage #%d (%s)\n", idx+1, arb.Type)
+
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "  Arbitrageur\t%s\n", arb.Arbitrageur)
+		fmt.Fprintf(tw, "  Amount in\t%s\n", r.amount(arb.TokenStart, arb.AmountIn))
+		fmt.Fprintf(tw, "  Amount out\t%s\n", r.amount(arb.TokenEnd, arb.AmountOut))
+		fmt.Fprintf(tw, "  Profit\t%s\n", r.amount(arb.ProfitToken, arb.Profit))
+		fmt.Fprintf(tw, "  Profit value\t%s\n", profitValue(arb))
+		fmt.Fprintf(tw, "  Gas\t%d @ %s wei\n", arb.GasUsed, orDash(arb.GasPrice))
+		if arb.PriorityFee != "" {
+			fmt.Fprintf(tw, "  Priority fee\t%s ETH%s\n", formatEther(arb.PriorityFee), profitShare(arb.PriorityFee, arb.ProfitETHWei))
+		}
+		for _, loan := range arb.FlashLoans {
+			fmt.Fprintf(tw, "  Flash loan\t%s from %s (%s), fee %s\n", r.amount(loan.Token, loan.Amount), loan.Lender, loan.Protocol, r.units(loan.Token, loan.Fee))
+		}
+		if arb.FlashLoanFees != "" {
+			fmt.Fprintf(tw, "  Flash loan fees\t%s\n", r.amount(arb.ProfitToken, arb.FlashLoanFees))
+		}
+		if arb.NetProfitWei != "" {
+			fmt.Fprintf(tw, "  Net profit\t%s ETH\n", formatEther(arb.NetProfitWei))
+		} else {
+			fmt.Fprintf(tw, "  Net profit\t- (no price for the profit token)\n")
+		}
+		tw.Flush()
+
+		fmt.Fprintln(w, "  Legs")
+		r.printLegs(w, arb.Legs)
+	}
+}
+
+// printLegs writes one line per swap leg
+func (r *txReport) printLegs(w io.Writer, legs []legReport) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for idx, leg := range legs {
+		if leg.TokenIn == "" {
+			fmt.Fprintf(tw, "  %d.\t%s\t%s\t(direction unknown)\n", idx+1, leg.Protocol, leg.Pool)
+			continue
+		}
+		fmt.Fprintf(tw, "  %d.\t%s\t%s\t%s\t->\t%s\n",
+			idx+1, leg.Protocol, leg.Pool, r.amount(leg.TokenIn, leg.AmountIn), r.amount(leg.TokenOut, leg.AmountOut))
+	}
+	tw.Flush()
+}
+
+// amount renders a decimal amount string of a token with its symbol
+func (r *txReport) amount(addr, value string) string {
+	v, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return value
+	}
+	return r.tokens.FormatAmount(common.HexToAddress(addr), v)
+}
+
+// units renders a decimal amount string of a token in whole units, without
+// the symbol
+func (r *txReport) units(addr, value string) string {
+	v, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return value
+	}
+	if t, ok := r.tokens.Lookup(common.HexToAddress(addr)); ok {
+		return token.FormatUnits(v, t.Decimals)
+	}
+	return value
+}
+
+// symbol returns the resolved symbol of a token, or "" if it's unknown
+func symbol(tokens *token.Registry, addr common.Address) string {
+	if t, ok := tokens.Lookup(addr); ok {
+		return t.Symbol
+	}
+	return ""
+}
+
+// profitValue renders the ETH and USD value of an arbitrage's profit
+func profitValue(arb arbReport) string {
+	var parts []string
+	if arb.ProfitETHWei != "" {
+		parts = append(parts, formatEther(arb.ProfitETHWei)+" ETH")
+	}
+	if arb.ProfitUSD != nil {
+		parts = append(parts, fmt.Sprintf("$%.2f", *arb.ProfitUSD))
+	}
+	if len(parts) == 0 {
+		return "- (no price for the profit token)"
+	}
+	return strings.Join(parts, " / ")
+}
+
+// profitShare renders what share of the profit a fee is, or "" when the
+// profit isn't known in ETH or isn't positive
+func profitShare(fee, profitWei string) string {
+	f, ok := new(big.Float).SetString(fee)
+	if !ok {
+		return ""
+	}
+	p, ok := new(big.Float).SetString(profitWei)
+	if !ok || p.Sign() <= 0 {
+		return ""
+	}
+	share, _ := new(big.Float).Quo(f, p).Float64()
+	return fmt.Sprintf(" (%.1f%% of profit)", share*100)
+}
+
+// bigString formats a big integer, keeping nil as the empty string
+func bigString(v *big.Int) string {
+	if v == nil {
+		return ""
+	}
+	return v.String()
+}
+
+// formatEther renders a decimal wei string in ETH
+func formatEther(wei string) string {
+	value, ok := new(big.Float).SetString(wei)
+	if !ok {
+		return wei
+	}
+	value.Quo(value, big.NewFloat(1e18))
+	return value.Text('f', 6)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -2,32 +2,55 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/rs/zerolog/log"
 
+	"github.com/devlongs/mev-inspector/internal/api"
 	"github.com/devlongs/mev-inspector/internal/arbitrage"
+	"github.com/devlongs/mev-inspector/internal/checkpoint"
 	"github.com/devlongs/mev-inspector/internal/config"
 	"github.com/devlongs/mev-inspector/internal/decoder"
 	"github.com/devlongs/mev-inspector/internal/eth"
+	"github.com/devlongs/mev-inspector/internal/liquidation"
+	"github.com/devlongs/mev-inspector/internal/metrics"
 	"github.com/devlongs/mev-inspector/internal/output"
+	"github.com/devlongs/mev-inspector/internal/pricing"
+	"github.com/devlongs/mev-inspector/internal/reorg"
+	"github.com/devlongs/mev-inspector/internal/sandwich"
+	"github.com/devlongs/mev-inspector/internal/token"
 	"github.com/devlongs/mev-inspector/pkg/types"
 )
 
 // Inspector is the main MEV inspection engine
 type Inspector struct {
-	client   *eth.Client
-	decoder  *decoder.Decoder
-	detector *arbitrage.Detector
-	logger   *output.Logger
-	cfg      *config.Config
-
-	lastBlock uint64
-	mu        sync.Mutex
+	client           *eth.Client
+	decoder          *decoder.Decoder
+	detector         *arbitrage.Detector
+	sandwichDetector *sandwich.Detector
+	liqDetector      *liquidation.Detector
+	logger           *output.Logger
+	sinks            *output.Dispatcher
+	apiServer        *api.Server
+	checkpoints      checkpoint.Store
+	reorgs           *reorg.Tracker
+	tokens           *token.Registry
+	pricer           *pricing.Pricer
+	cfg              *config.Config
+
+	lastBlock  uint64
+	mu         sync.Mutex
+	subscribed atomic.Bool // New heads arrive over the WebSocket subscription
 }
 
 // NewInspector creates a new MEV inspector
@@ -38,19 +61,78 @@ func NewInspector(cfg *config.Config) (*Inspector, error) {
 	}
 
 	// Create decoder with enabled DEXes
-	dec := decoder.NewDecoder(client, cfg.Inspector.EnableUniswapV2, cfg.Inspector.EnableUniswapV3)
+	dec, err := decoder.NewDecoder(client, cfg.Inspector.Protocols)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
 
 	// Create arbitrage detector
 	det := arbitrage.NewDetector(client)
 
-	lgr := output.NewLogger(cfg.Logging)
+	// Create sandwich detector
+	sandwichDet := sandwich.NewDetector(client)
+
+	// Create liquidation detector
+	liqDet := liquidation.NewDetector(client)
+
+	// Value profits in ETH and USD
+	pricer, err := pricing.NewPricer(cfg.Pricing)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	// Resolve token symbols and decimals for readable output
+	tokens, err := token.NewRegistry(client, cfg.Tokens.CachePath)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	// Fan results out to every configured sink
+	lgr := output.NewLogger(cfg.Output.Log, tokens)
+
+	// Serve recent results over HTTP
+	var buffer *api.Buffer
+	var apiServer *api.Server
+	if cfg.API.Enabled {
+		buffer = api.NewBuffer(cfg.API.BufferBlocks)
+		apiServer = api.NewServer(cfg.API.ListenAddr, buffer, lgr.GetStats)
+	}
+
+	sinks, err := newSinks(cfg, lgr, buffer)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	// Create checkpoint store for resuming after restarts
+	var store checkpoint.Store
+	if cfg.Checkpoint.Enabled {
+		store = checkpoint.NewFileStore(cfg.Checkpoint.Path)
+	}
+
+	// Track recent block hashes to detect reorgs
+	var tracker *reorg.Tracker
+	if cfg.Inspector.ReorgDepth > 0 {
+		tracker = reorg.NewTracker(cfg.Inspector.ReorgDepth)
+	}
 
 	return &Inspector{
-		client:   client,
-		decoder:  dec,
-		detector: det,
-		logger:   lgr,
-		cfg:      cfg,
+		client:           client,
+		decoder:          dec,
+		detector:         det,
+		sandwichDetector: sandwichDet,
+		liqDetector:      liqDet,
+		logger:           lgr,
+		sinks:            output.NewDispatcher(cfg.Output.QueueSize, sinks...),
+		apiServer:        apiServer,
+		checkpoints:      store,
+		reorgs:           tracker,
+		tokens:           tokens,
+		pricer:           pricer,
+		cfg:              cfg,
 	}, nil
 }
 
@@ -63,18 +145,55 @@ func (i *Inspector) Start(ctx context.Context) error {
 		return err
 	}
 
-	// Set starting block
+	// Set starting block: checkpoint, then configured start block, then head
+	startSource := "head"
 	if i.cfg.Inspector.StartBlock > 0 {
 		i.lastBlock = i.cfg.Inspector.StartBlock - 1
+		startSource = "config"
 	} else {
 		i.lastBlock = currentBlock - 1
 	}
 
+	if i.checkpoints != nil && i.cfg.Checkpoint.Resume {
+		saved, ok, err := i.checkpoints.Load()
+		if err != nil {
+			return err
+		}
+		if ok {
+			i.lastBlock = saved
+			startSource = "checkpoint"
+		}
+	}
+
 	log.Info().
 		Uint64("startBlock", i.lastBlock+1).
 		Uint64("currentBlock", currentBlock).
+		Str("source", startSource).
 		Msg("Inspector initialized")
 
+	if i.apiServer != nil {
+		go func() {
+			if err := i.apiServer.Run(ctx); err != nil {
+				log.Error().Err(err).Msg("API server stopped")
+			}
+		}()
+	}
+
+	if i.cfg.Metrics.Enabled {
+		go func() {
+			if err := metrics.Serve(ctx, i.cfg.Metrics.ListenAddr); err != nil {
+				log.Error().Err(err).Msg("Metrics server stopped")
+			}
+		}()
+	}
+
+	// React to new heads as they arrive when a WebSocket URL is configured,
+	// and poll while there is no subscription
+	heads := make(chan uint64, 1)
+	if i.cfg.RPC.WSUrl != "" {
+		go i.watchHeads(ctx, heads)
+	}
+
 	// Create ticker for polling
 	ticker := time.NewTicker(i.cfg.Inspector.PollInterval)
 	defer ticker.Stop()
@@ -93,27 +212,33 @@ func (i *Inspector) Start(ctx context.Context) error {
 		case <-statsTicker.C:
 			i.logger.LogStats()
 
+		case head := <-heads:
+			if err := i.catchUp(ctx, head); err != nil {
+				i.sinks.OnError(err, "processing blocks")
+			}
+
 		case <-ticker.C:
-			if err := i.processNewBlocks(ctx); err != nil {
-				i.logger.LogError(err, "processing blocks")
+			if i.subscribed.Load() {
+				continue
+			}
+			if err := i.catchUp(ctx, 0); err != nil {
+				i.sinks.OnError(err, "processing blocks")
 			}
 		}
 	}
 }
 
-// processNewBlocks fetches and processes any new blocks
-func (i *Inspector) processNewBlocks(ctx context.Context) error {
-	currentBlock, err := i.client.BlockNumber(ctx)
-	if err != nil {
-		return err
-	}
-
+// processNewBlocks processes the next batch of blocks up to currentBlock. It
+// returns true once the last processed block has reached currentBlock.
+func (i *Inspector) processNewBlocks(ctx context.Context, currentBlock uint64) (bool, error) {
 	i.mu.Lock()
 	fromBlock := i.lastBlock + 1
 	i.mu.Unlock()
 
+	metrics.SetHead(currentBlock, fromBlock-1)
+
 	if currentBlock < fromBlock {
-		return nil // No new blocks
+		return true, nil // No new blocks
 	}
 
 	// Process blocks in batches
@@ -128,106 +253,287 @@ func (i *Inspector) processNewBlocks(ctx context.Context) error {
 		Msg("Processing block range")
 
 	if err := i.processBlockRange(ctx, fromBlock, toBlock); err != nil {
-		return err
+		if errors.Is(err, errChainReorged) {
+			return false, i.handleReorg(ctx, fromBlock-1)
+		}
+		return false, err
+	}
+
+	// The range only counts as processed once the durable sinks have it
+	if err := i.sinks.Flush(); err != nil {
+		return false, fmt.Errorf("failed to deliver blocks %d-%d: %w", fromBlock, toBlock, err)
 	}
 
 	i.mu.Lock()
 	i.lastBlock = toBlock
 	i.mu.Unlock()
 
-	return nil
+	metrics.SetHead(currentBlock, toBlock)
+
+	// Persist progress only after the whole batch has been processed
+	if i.checkpoints != nil {
+		if err := i.checkpoints.Save(toBlock); err != nil {
+			return false, err
+		}
+	}
+
+	return toBlock == currentBlock, nil
 }
 
+// errChainReorged means the first block of a range doesn't build on the last
+// processed block
+var errChainReorged = errors.New("chain reorganized below the block range")
+
 // processBlockRange processes a range of blocks
 func (i *Inspector) processBlockRange(ctx context.Context, fromBlock, toBlock uint64) error {
-	startTime := time.Now()
+	// Fetch block headers for reorg tracking and storage before the logs, so
+	// a reorg in between shows up as logs from a block with another hash
+	var headers map[uint64]*eth.BlockHeader
+	if i.reorgs != nil || i.cfg.Storage.Enabled {
+		var err error
+		if headers, err = i.fetchHeaders(ctx, fromBlock, toBlock); err != nil {
+			return err
+		}
+
+		// The first new block must build on the last processed one,
+		// otherwise the chain was reorganized underneath us
+		if i.reorgs != nil && !i.reorgs.ExtendsChain(headers[fromBlock].Header) {
+			return errChainReorged
+		}
+	}
 
 	// Fetch all swap logs in the range
 	logs, err := i.decoder.GetAllSwapLogs(ctx, fromBlock, toBlock)
 	if err != nil {
 		return err
 	}
+	if err := checkLogHashes(headers, logs); err != nil {
+		return err
+	}
 
-	if len(logs) == 0 {
-		for block := fromBlock; block <= toBlock; block++ {
-			i.logger.LogBlockComplete(block, 0, 0, time.Since(startTime))
-		}
-		return nil
+	// Decode and detect concurrently, results come back in block/tx order
+	results, err := i.processTransactions(ctx, logs)
+	if err != nil {
+		return err
+	}
+	i.resolveTokens(ctx, resultTokens(results))
+	i.priceArbitrages(results)
+
+	// Attribute every result to the block it came from
+	blocks := make(map[uint64]*types.BlockResult)
+	for block := fromBlock; block <= toBlock; block++ {
+		blocks[block] = &types.BlockResult{Number: block}
 	}
 
-	// Group logs by transaction
-	txLogs := i.decoder.GroupSwapsByTransaction(logs)
+	// Collect all decoded swaps for cross-transaction detection
+	var rangeSwaps []types.Swap
+	txSwaps := make(map[common.Hash][]types.Swap)
 
-	totalSwaps := 0
-	totalArbitrages := 0
+	// A block's processing time is the wall time from its first transaction
+	// starting to its last finishing; workers overlap, so their durations
+	// can't be summed
+	started := make(map[uint64]time.Time)
+	finished := make(map[uint64]time.Time)
 
-	// Process each transaction
-	for txHash, txSwapLogs := range txLogs {
-		swaps, err := i.decoder.DecodeSwapsForTransaction(ctx, txSwapLogs)
-		if err != nil {
-			i.logger.LogError(err, "decoding swaps")
-			continue
+	for _, result := range results {
+		if start, ok := started[result.block]; !ok || result.started.Before(start) {
+			started[result.block] = result.started
+		}
+		if result.finished.After(finished[result.block]) {
+			finished[result.block] = result.finished
 		}
 
-		totalSwaps += len(swaps)
-
-		// Log individual swaps at debug level
-		for _, swap := range swaps {
-			i.logger.LogSwap(&swap)
+		if result.err != nil {
+			i.sinks.OnError(result.err, result.errContext)
+			if result.swaps == nil {
+				continue
+			}
 		}
 
-		// Detect arbitrage
-		arbitrages, err := i.detector.DetectArbitrage(ctx, txHash, swaps)
-		if err != nil {
-			i.logger.LogError(err, "detecting arbitrage")
-			continue
+		rangeSwaps = append(rangeSwaps, result.swaps...)
+		txSwaps[result.txHash] = result.swaps
+
+		for idx := range result.swaps {
+			if br, ok := blocks[result.swaps[idx].BlockNumber]; ok {
+				br.Swaps = append(br.Swaps, result.swaps[idx])
+			}
 		}
 
-		for _, arb := range arbitrages {
+		for idx := range result.arbitrages {
+			arb := &result.arbitrages[idx]
 			// Filter by profitability if configured
-			if i.cfg.Inspector.OnlyProfitable && !i.detector.IsProfitable(&arb) {
+			if i.cfg.Inspector.OnlyProfitable && !i.detector.IsProfitable(arb) {
 				continue
 			}
-			i.logger.LogArbitrage(&arb)
-			totalArbitrages++
+			if br, ok := blocks[arb.BlockNumber]; ok {
+				br.Arbitrages = append(br.Arbitrages, *arb)
+			}
 		}
 	}
 
-	// Log completion for each block in range
-	duration := time.Since(startTime)
-	blocksProcessed := toBlock - fromBlock + 1
-	avgSwapsPerBlock := totalSwaps / int(blocksProcessed)
-	avgArbsPerBlock := totalArbitrages / int(blocksProcessed)
+	for number, start := range started {
+		if br, ok := blocks[number]; ok {
+			br.ProcessingTime = finished[number].Sub(start)
+		}
+	}
 
+	// Detect sandwiches across transactions in the range
+	if i.cfg.Inspector.DetectSandwiches {
+		for _, sw := range i.sandwichDetector.DetectSandwiches(ctx, rangeSwaps) {
+			if br, ok := blocks[sw.BlockNumber]; ok {
+				br.Sandwiches = append(br.Sandwiches, sw)
+			}
+		}
+	}
+
+	// Detect liquidations and join them with swaps from the same transaction
+	if i.cfg.Inspector.DetectLiquidations {
+		liquidations, err := i.processLiquidations(ctx, fromBlock, toBlock, headers, txSwaps)
+		if err != nil {
+			return err
+		}
+		for _, liq := range liquidations {
+			if br, ok := blocks[liq.BlockNumber]; ok {
+				br.Liquidations = append(br.Liquidations, liq)
+			}
+		}
+	}
+
+	for number, header := range headers {
+		blocks[number].Hash = header.Hash
+		blocks[number].ParentHash = header.ParentHash
+	}
+
+	// Remember blocks so later polls can detect reorgs
+	if i.reorgs != nil {
+		for _, block := range blocks {
+			i.reorgs.Record(block)
+		}
+	}
+
+	// Publish each block in order, only now that the whole range succeeded,
+	// so a retried range doesn't publish its swaps and arbitrages twice
 	for block := fromBlock; block <= toBlock; block++ {
-		i.logger.LogBlockComplete(block, avgSwapsPerBlock, avgArbsPerBlock, duration/time.Duration(blocksProcessed))
+		br := blocks[block]
+		for idx := range br.Swaps {
+			i.sinks.OnSwap(&br.Swaps[idx])
+		}
+		for idx := range br.Arbitrages {
+			i.sinks.OnArbitrage(&br.Arbitrages[idx])
+		}
+		i.sinks.OnBlock(br)
 	}
 
 	return nil
 }
 
-// ProcessSingleBlock processes a single block (useful for testing)
-func (i *Inspector) ProcessSingleBlock(ctx context.Context, blockNumber uint64) ([]types.Arbitrage, error) {
-	logs, err := i.decoder.GetAllSwapLogs(ctx, blockNumber, blockNumber)
+// processLiquidations detects liquidations in a block range and attaches the
+// decoded swaps of each liquidation transaction
+func (i *Inspector) processLiquidations(ctx context.Context, fromBlock, toBlock uint64, headers map[uint64]*eth.BlockHeader, txSwaps map[common.Hash][]types.Swap) ([]types.Liquidation, error) {
+	logs, err := i.liqDetector.GetLiquidationLogs(ctx, fromBlock, toBlock)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkLogHashes(headers, logs); err != nil {
+		return nil, err
+	}
 
-	var allArbitrages []types.Arbitrage
+	var liquidations []types.Liquidation
+	var tokens []common.Address
 
-	txLogs := i.decoder.GroupSwapsByTransaction(logs)
-	for txHash, txSwapLogs := range txLogs {
-		swaps, err := i.decoder.DecodeSwapsForTransaction(ctx, txSwapLogs)
+	for _, liqLog := range logs {
+		liq, err := i.liqDetector.DecodeLiquidationLog(ctx, liqLog)
 		if err != nil {
+			i.sinks.OnError(err, "decoding liquidation")
 			continue
 		}
 
-		arbitrages, err := i.detector.DetectArbitrage(ctx, txHash, swaps)
-		if err != nil {
-			continue
+		i.liqDetector.AttachSwaps(liq, txSwaps[liq.TxHash])
+		liquidations = append(liquidations, *liq)
+		tokens = append(tokens, liq.CollateralAsset, liq.DebtAsset)
+	}
+	i.resolveTokens(ctx, tokens)
+
+	return liquidations, nil
+}
+
+// fetchHeaders fetches the headers of a block range in batches and checks
+// that they form a chain
+func (i *Inspector) fetchHeaders(ctx context.Context, fromBlock, toBlock uint64) (map[uint64]*eth.BlockHeader, error) {
+	list, err := i.client.HeaderRange(ctx, fromBlock, toBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[uint64]*eth.BlockHeader, len(list))
+	for idx, header := range list {
+		if idx > 0 && header.ParentHash != list[idx-1].Hash {
+			return nil, fmt.Errorf("block %d does not extend block %d: chain reorganized while fetching headers", header.Number, list[idx-1].Number)
+		}
+		headers[fromBlock+uint64(idx)] = header
+	}
+
+	return headers, nil
+}
+
+// checkLogHashes fails if a log comes from a different block than the header
+// fetched for its number, which means the chain was reorganized in between.
+// The range is then retried and the reorg handled on the next poll.
+func checkLogHashes(headers map[uint64]*eth.BlockHeader, logs []ethtypes.Log) error {
+	if headers == nil {
+		return nil
+	}
+	for _, l := range logs {
+		if header, ok := headers[l.BlockNumber]; ok && header.Hash != l.BlockHash {
+			return fmt.Errorf("log of block %d has hash %s, header has %s: chain reorganized while fetching logs", l.BlockNumber, l.BlockHash.Hex(), header.Hash.Hex())
+		}
+	}
+	return nil
+}
+
+// handleReorg finds the last canonical block at or below tip, retracts the
+// results of every replaced block and rewinds so they are processed again
+func (i *Inspector) handleReorg(ctx context.Context, tip uint64) error {
+	ancestor, err := i.reorgs.FindCommonAncestor(ctx, i.client, tip)
+	if err != nil {
+		if !errors.Is(err, reorg.ErrTooDeep) {
+			return err
 		}
+		// Rewind as far as we can; older results can't be verified
+		i.sinks.OnError(err, "finding reorg common ancestor")
+	}
+
+	i.sinks.OnReorg(ancestor, tip, i.reorgs.Rollback(ancestor+1))
 
-		allArbitrages = append(allArbitrages, arbitrages...)
+	i.mu.Lock()
+	i.lastBlock = ancestor
+	i.mu.Unlock()
+
+	if i.checkpoints != nil {
+		if err := i.checkpoints.Save(ancestor); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ProcessSingleBlock processes a single block (useful for testing)
+func (i *Inspector) ProcessSingleBlock(ctx context.Context, blockNumber uint64) ([]types.Arbitrage, error) {
+	logs, err := i.decoder.GetAllSwapLogs(ctx, blockNumber, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := i.processTransactions(ctx, logs)
+	if err != nil {
+		return nil, err
+	}
+	i.priceArbitrages(results)
+
+	var allArbitrages []types.Arbitrage
+	for _, result := range results {
+		allArbitrages = append(allArbitrages, result.arbitrages...)
 	}
 
 	return allArbitrages, nil
@@ -235,15 +541,48 @@ func (i *Inspector) ProcessSingleBlock(ctx context.Context, blockNumber uint64)
 
 // Close shuts down the inspector
 func (i *Inspector) Close() {
+	i.sinks.Close()
 	i.client.Close()
 }
 
-func main() {
-	// Load configuration
+// loadConfig loads the configuration and sets up logging, then reports any
+// deprecated settings it contained
+func loadConfig() *config.Config {
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to load configuration")
 	}
+	output.ConfigureLogging(cfg.Logging)
+
+	for _, warning := range cfg.Warnings {
+		log.Warn().Msg(warning)
+	}
+	return cfg
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "inspect":
+			runInspect(os.Args[2:])
+			return
+		case "backfill":
+			runBackfill(os.Args[2:])
+			return
+		}
+	}
+
+	startBlock := flag.Uint64("start-block", 0, "start from this block, ignoring any saved checkpoint")
+	flag.Parse()
+
+	// Load configuration
+	cfg := loadConfig()
+
+	// An explicit start block overrides the checkpoint
+	if *startBlock > 0 {
+		cfg.Inspector.StartBlock = *startBlock
+		cfg.Checkpoint.Resume = false
+	}
 
 	// Create inspector
 	inspector, err := NewInspector(cfg)
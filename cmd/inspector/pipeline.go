@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/devlongs/mev-inspector/internal/pricing"
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+// txResult holds the outcome of processing a single transaction
+type txResult struct {
+	txHash     common.Hash
+	block      uint64
+	swaps      []types.Swap
+	arbitrages []types.Arbitrage
+	started    time.Time
+	finished   time.Time
+	err        error
+	errContext string
+}
+
+// processTransactions decodes swaps and detects arbitrage for every
+// transaction in logs using a bounded pool of WorkerCount workers. Logs must
+// be sorted by block and log index; results are returned in that same order
+// regardless of which worker finishes first.
+func (i *Inspector) processTransactions(ctx context.Context, logs []ethtypes.Log) ([]txResult, error) {
+	// Resolve new pools in bulk instead of one by one in the workers
+	i.decoder.PrefetchPools(ctx, logs)
+
+	txLogs := i.decoder.GroupSwapsByTransaction(logs)
+
+	// Order transactions by their first swap log
+	order := make([]common.Hash, 0, len(txLogs))
+	seen := make(map[common.Hash]bool, len(txLogs))
+	for _, l := range logs {
+		if !seen[l.TxHash] {
+			seen[l.TxHash] = true
+			order = append(order, l.TxHash)
+		}
+	}
+
+	workers := i.cfg.Inspector.WorkerCount
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(order) {
+		workers = len(order)
+	}
+
+	results := make([]txResult, len(order))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				txHash := order[idx]
+				started := time.Now()
+				results[idx] = i.processTransaction(ctx, txHash, txLogs[txHash])
+				results[idx].started = started
+				results[idx].finished = time.Now()
+			}
+		}()
+	}
+
+	var err error
+dispatch:
+	for idx := range order {
+		select {
+		case jobs <- idx:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// processTransaction decodes the swaps of one transaction and runs
+// arbitrage detection, including gas enrichment
+func (i *Inspector) processTransaction(ctx context.Context, txHash common.Hash, logs []ethtypes.Log) txResult {
+	result := txResult{txHash: txHash}
+	if len(logs) > 0 {
+		result.block = logs[0].BlockNumber
+	}
+
+	swaps, err := i.decoder.DecodeSwapsForTransaction(ctx, logs)
+	if err != nil {
+		result.err = err
+		result.errContext = "decoding swaps"
+		return result
+	}
+	result.swaps = swaps
+
+	arbitrages, err := i.detector.DetectArbitrage(ctx, txHash, swaps)
+	if err != nil {
+		result.err = err
+		result.errContext = "detecting arbitrage"
+		return result
+	}
+	result.arbitrages = arbitrages
+
+	return result
+}
+
+// resultTokens lists the tokens swapped or borrowed in results
+func resultTokens(results []txResult) []common.Address {
+	var tokens []common.Address
+	for _, result := range results {
+		for _, swap := range result.swaps {
+			tokens = append(tokens, swap.Token0, swap.Token1)
+		}
+		for _, arb := range result.arbitrages {
+			for _, loan := range arb.FlashLoans {
+				tokens = append(tokens, loan.Token)
+			}
+		}
+	}
+	return tokens
+}
+
+// resolveTokens fetches the metadata of tokens the registry hasn't seen yet
+// so sinks can render symbols and amounts. A failure only makes the output
+// less readable.
+func (i *Inspector) resolveTokens(ctx context.Context, tokens []common.Address) {
+	if err := i.tokens.Resolve(ctx, tokens); err != nil {
+		i.sinks.OnError(err, "resolving tokens")
+	}
+}
+
+// priceArbitrages values the profit of every arbitrage in results at the
+// prices implied by the swaps of its block
+func (i *Inspector) priceArbitrages(results []txResult) {
+	blockSwaps := make(map[uint64][]types.Swap)
+	for _, result := range results {
+		blockSwaps[result.block] = append(blockSwaps[result.block], result.swaps...)
+	}
+
+	books := make(map[uint64]*pricing.Book)
+	for idx := range results {
+		for j := range results[idx].arbitrages {
+			arb := &results[idx].arbitrages[j]
+			book, ok := books[arb.BlockNumber]
+			if !ok {
+				book = pricing.NewBook(blockSwaps[arb.BlockNumber])
+				books[arb.BlockNumber] = book
+			}
+			i.pricer.Value(book, arb)
+		}
+	}
+}
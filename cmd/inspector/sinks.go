@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+
+	"github.com/devlongs/mev-inspector/internal/api"
+	"github.com/devlongs/mev-inspector/internal/config"
+	"github.com/devlongs/mev-inspector/internal/metrics"
+	"github.com/devlongs/mev-inspector/internal/output"
+	"github.com/devlongs/mev-inspector/internal/storage"
+)
+
+// newSinks creates every output sink enabled in cfg
+func newSinks(cfg *config.Config, logger *output.Logger, buffer *api.Buffer) ([]output.Sink, error) {
+	// The logger always runs since it keeps the statistics; output.log only
+	// controls whether it writes
+	sinks := []output.Sink{logger}
+
+	if cfg.Output.FilePath != "" {
+		file, err := output.NewFileSink(cfg.Output.FilePath)
+		if err != nil {
+			closeSinks(sinks)
+			return nil, err
+		}
+		sinks = append(sinks, file)
+	}
+
+	if cfg.Output.WebhookURL != "" {
+		sinks = append(sinks, output.NewWebhookSink(cfg.Output.WebhookURL, cfg.Output.WebhookTimeout))
+	}
+
+	if cfg.Metrics.Enabled {
+		sinks = append(sinks, metrics.NewSink())
+	}
+
+	// Keep recent results in memory for the API
+	if buffer != nil {
+		sinks = append(sinks, buffer)
+	}
+
+	// Open the results database
+	if cfg.Storage.Enabled {
+		db, err := storage.Open(context.Background(), cfg.Storage)
+		if err != nil {
+			closeSinks(sinks)
+			return nil, err
+		}
+		sinks = append(sinks, db)
+	}
+
+	return sinks, nil
+}
+
+// closeSinks closes sinks that were created before a later one failed
+func closeSinks(sinks []output.Sink) {
+	for _, sink := range sinks {
+		sink.Close()
+	}
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+// Buffer is an output sink keeping the results of the most recent blocks in
+// a fixed-size ring for the API to query
+type Buffer struct {
+	entries []*types.BlockResult
+	start   int // Index of the oldest block
+	count   int
+	mu      sync.RWMutex
+}
+
+// ArbitrageFilter selects arbitrages from the buffer. Zero values match
+// everything.
+type ArbitrageFilter struct {
+	FromBlock   uint64
+	ToBlock     uint64 // 0 = no upper bound
+	Arbitrageur common.Address
+	Token       common.Address // Matches the profit token or any token traded on the path
+}
+
+// TxResults holds everything detected in a single transaction
+type TxResults struct {
+	BlockNumber  uint64
+	Swaps        []types.Swap
+	Arbitrages   []types.Arbitrage
+	Sandwiches   []types.Sandwich // Sandwiches the transaction was part of
+	Liquidations []types.Liquidation
+}
+
+// NewBuffer creates a buffer holding up to size blocks
+func NewBuffer(size int) *Buffer {
+	if size < 1 {
+		size = 1
+	}
+
+	return &Buffer{
+		entries: make([]*types.BlockResult, size),
+	}
+}
+
+// Name returns the sink name
+func (b *Buffer) Name() string {
+	return "api"
+}
+
+// OnSwap implements output.Sink; swaps are buffered with their block
+func (b *Buffer) OnSwap(swap *types.Swap) error {
+	return nil
+}
+
+// OnArbitrage implements output.Sink; arbitrages are buffered with their block
+func (b *Buffer) OnArbitrage(arb *types.Arbitrage) error {
+	return nil
+}
+
+// OnBlock adds a block, evicting the oldest one when the buffer is full
+func (b *Buffer) OnBlock(block *types.BlockResult) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.count == len(b.entries) {
+		b.entries[b.start] = block
+		b.start = (b.start + 1) % len(b.entries)
+		return nil
+	}
+
+	b.entries[(b.start+b.count)%len(b.entries)] = block
+	b.count++
+	return nil
+}
+
+// OnReorg drops every buffered block above ancestor
+func (b *Buffer) OnReorg(ancestor, tip uint64, retracted []*types.BlockResult) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for b.count > 0 {
+		newest := (b.start + b.count - 1) % len(b.entries)
+		if b.entries[newest].Number <= ancestor {
+			break
+		}
+		b.entries[newest] = nil
+		b.count--
+	}
+	return nil
+}
+
+// OnError implements output.Sink
+func (b *Buffer) OnError(err error, context string) error {
+	return nil
+}
+
+// Close implements output.Sink
+func (b *Buffer) Close() error {
+	return nil
+}
+
+// Block returns a buffered block by number
+func (b *Buffer) Block(number uint64) (*types.BlockResult, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for k := 0; k < b.count; k++ {
+		block := b.entries[(b.start+k)%len(b.entries)]
+		if block.Number == number {
+			return block, true
+		}
+	}
+	return nil, false
+}
+
+// Arbitrages returns the buffered arbitrages matching filter, oldest first
+func (b *Buffer) Arbitrages(filter ArbitrageFilter) []types.Arbitrage {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var arbitrages []types.Arbitrage
+	for k := 0; k < b.count; k++ {
+		block := b.entries[(b.start+k)%len(b.entries)]
+		if block.Number < filter.FromBlock || (filter.ToBlock != 0 && block.Number > filter.ToBlock) {
+			continue
+		}
+
+		for _, arb := range block.Arbitrages {
+			if filter.Arbitrageur != (common.Address{}) && arb.Arbitrageur != filter.Arbitrageur {
+				continue
+			}
+			if filter.Token != (common.Address{}) && !tradesToken(&arb, filter.Token) {
+				continue
+			}
+			arbitrages = append(arbitrages, arb)
+		}
+	}
+
+	return arbitrages
+}
+
+// Tx returns everything buffered for a transaction
+func (b *Buffer) Tx(hash common.Hash) (*TxResults, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for k := 0; k < b.count; k++ {
+		block := b.entries[(b.start+k)%len(b.entries)]
+		result := &TxResults{BlockNumber: block.Number}
+		found := false
+
+		for _, swap := range block.Swaps {
+			if swap.TxHash == hash {
+				result.Swaps = append(result.Swaps, swap)
+				found = true
+			}
+		}
+		for _, arb := range block.Arbitrages {
+			if arb.TxHash == hash {
+				result.Arbitrages = append(result.Arbitrages, arb)
+				found = true
+			}
+		}
+		for _, sw := range block.Sandwiches {
+			if inSandwich(&sw, hash) {
+				result.Sandwiches = append(result.Sandwiches, sw)
+				found = true
+			}
+		}
+		for _, liq := range block.Liquidations {
+			if liq.TxHash == hash {
+				result.Liquidations = append(result.Liquidations, liq)
+				found = true
+			}
+		}
+
+		if found {
+			return result, true
+		}
+	}
+
+	return nil, false
+}
+
+// tradesToken reports whether an arbitrage's profit token or any leg uses token
+func tradesToken(arb *types.Arbitrage, token common.Address) bool {
+	if arb.ProfitToken == token || arb.TokenStart == token || arb.TokenEnd == token {
+		return true
+	}
+	for _, swap := range arb.Path {
+		if swap.Token0 == token || swap.Token1 == token {
+			return true
+		}
+	}
+	return false
+}
+
+// inSandwich reports whether a transaction is the frontrun, backrun or a
+// victim of a sandwich
+func inSandwich(sw *types.Sandwich, hash common.Hash) bool {
+	if sw.Frontrun.TxHash == hash || sw.Backrun.TxHash == hash {
+		return true
+	}
+	for _, victim := range sw.Victims {
+		if victim.TxHash == hash {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,307 @@
+package api
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/devlongs/mev-inspector/internal/output"
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+// API response types. Token amounts, wei values and other big integers are
+// rendered as decimal strings so JSON clients don't lose precision.
+
+type swapJSON struct {
+	TxHash      string `json:"txHash"`
+	BlockNumber uint64 `json:"blockNumber"`
+	LogIndex    uint   `json:"logIndex"`
+	Protocol    string `json:"protocol"`
+	Pool        string `json:"pool"`
+	PoolID      string `json:"poolId,omitempty"`
+	Sender      string `json:"sender"`
+	Recipient   string `json:"recipient"`
+	Token0      string `json:"token0"`
+	Token1      string `json:"token1"`
+	Amount0In   string `json:"amount0In"`
+	Amount1In   string `json:"amount1In"`
+	Amount0Out  string `json:"amount0Out"`
+	Amount1Out  string `json:"amount1Out"`
+}
+
+type flashLoanJSON struct {
+	Protocol string `json:"protocol"`
+	Lender   string `json:"lender"`
+	Borrower string `json:"borrower"`
+	Token    string `json:"token"`
+	Amount   string `json:"amount"`
+	Fee      string `json:"fee"`
+}
+
+type arbitrageJSON struct {
+	Type          string          `json:"type"`
+	TxHash        string          `json:"txHash"`
+	BlockNumber   uint64          `json:"blockNumber"`
+	Arbitrageur   string          `json:"arbitrageur"`
+	Path          []swapJSON      `json:"path"`
+	TokenStart    string          `json:"tokenStart"`
+	TokenEnd      string          `json:"tokenEnd"`
+	AmountIn      string          `json:"amountIn"`
+	AmountOut     string          `json:"amountOut"`
+	Profit        string          `json:"profit"`
+	ProfitToken   string          `json:"profitToken"`
+	GasUsed       uint64          `json:"gasUsed"`
+	GasPrice      *string         `json:"gasPrice"`
+	BaseFee       *string         `json:"baseFee"`
+	BurnedFee     *string         `json:"burnedFee"`
+	PriorityFee   *string         `json:"priorityFee"`
+	BlobFee       *string         `json:"blobFee,omitempty"`
+	NetProfitWei  *string         `json:"netProfitWei"`
+	ProfitETHWei  *string         `json:"profitEthWei"`
+	ProfitUSD     *float64        `json:"profitUsd"`
+	FlashLoans    []flashLoanJSON `json:"flashLoans,omitempty"`
+	FlashLoanFees *string         `json:"flashLoanFees,omitempty"`
+}
+
+type sandwichJSON struct {
+	BlockNumber uint64     `json:"blockNumber"`
+	Attacker    string     `json:"attacker"`
+	Pool        string     `json:"pool"`
+	PoolID      string     `json:"poolId,omitempty"`
+	Frontrun    swapJSON   `json:"frontrun"`
+	Victims     []swapJSON `json:"victims"`
+	Backrun     swapJSON   `json:"backrun"`
+	ProfitToken string     `json:"profitToken"`
+	Profit      string     `json:"profit"`
+	VictimLoss  *string    `json:"victimLoss"`
+}
+
+type liquidationJSON struct {
+	TxHash           string     `json:"txHash"`
+	BlockNumber      uint64     `json:"blockNumber"`
+	LogIndex         uint       `json:"logIndex"`
+	Protocol         string     `json:"protocol"`
+	Liquidator       string     `json:"liquidator"`
+	Borrower         string     `json:"borrower"`
+	Absorbed         bool       `json:"absorbed"`
+	CollateralAsset  string     `json:"collateralAsset"`
+	CollateralAmount string     `json:"collateralAmount"`
+	DebtAsset        string     `json:"debtAsset"`
+	DebtRepaid       string     `json:"debtRepaid"`
+	Swaps            []swapJSON `json:"swaps"`
+	Profit           *string    `json:"profit"`
+	ProfitToken      string     `json:"profitToken"`
+}
+
+type blockJSON struct {
+	Number         uint64            `json:"number"`
+	Hash           string            `json:"hash"`
+	ParentHash     string            `json:"parentHash"`
+	ProcessingTime string            `json:"processingTime"`
+	Swaps          []swapJSON        `json:"swaps"`
+	Arbitrages     []arbitrageJSON   `json:"arbitrages"`
+	Sandwiches     []sandwichJSON    `json:"sandwiches"`
+	Liquidations   []liquidationJSON `json:"liquidations"`
+}
+
+type txJSON struct {
+	TxHash       string            `json:"txHash"`
+	BlockNumber  uint64            `json:"blockNumber"`
+	Swaps        []swapJSON        `json:"swaps"`
+	Arbitrages   []arbitrageJSON   `json:"arbitrages"`
+	Sandwiches   []sandwichJSON    `json:"sandwiches"`
+	Liquidations []liquidationJSON `json:"liquidations"`
+}
+
+type statsJSON struct {
+	BlocksProcessed   uint64  `json:"blocksProcessed"`
+	SwapsDetected     uint64  `json:"swapsDetected"`
+	ArbitragesFound   uint64  `json:"arbitragesFound"`
+	SandwichesFound   uint64  `json:"sandwichesFound"`
+	LiquidationsFound uint64  `json:"liquidationsFound"`
+	FlashLoanArbs     uint64  `json:"flashLoanArbs"`
+	ReorgsDetected    uint64  `json:"reorgsDetected"`
+	ResultsRetracted  uint64  `json:"resultsRetracted"`
+	UnpricedArbs      uint64  `json:"unpricedArbitrages"`
+	TotalProfitWei    string  `json:"totalProfitWei"`
+	TotalNetProfit    string  `json:"totalNetProfitWei"`
+	TotalProfitUSD    float64 `json:"totalProfitUsd"`
+	StartTime         string  `json:"startTime"`
+	Uptime            string  `json:"uptime"`
+}
+
+type errorJSON struct {
+	Error string `json:"error"`
+}
+
+func newSwapJSON(swap *types.Swap) swapJSON {
+	s := swapJSON{
+		TxHash:      swap.TxHash.Hex(),
+		BlockNumber: swap.BlockNumber,
+		LogIndex:    swap.LogIndex,
+		Protocol:    swap.Protocol,
+		Pool:        swap.Pool.Hex(),
+		Sender:      swap.Sender.Hex(),
+		Recipient:   swap.Recipient.Hex(),
+		Token0:      swap.Token0.Hex(),
+		Token1:      swap.Token1.Hex(),
+		Amount0In:   decimal(swap.Amount0In),
+		Amount1In:   decimal(swap.Amount1In),
+		Amount0Out:  decimal(swap.Amount0Out),
+		Amount1Out:  decimal(swap.Amount1Out),
+	}
+	if swap.PoolID != (common.Hash{}) {
+		s.PoolID = swap.PoolID.Hex()
+	}
+	return s
+}
+
+func newSwapsJSON(swaps []types.Swap) []swapJSON {
+	out := make([]swapJSON, 0, len(swaps))
+	for idx := range swaps {
+		out = append(out, newSwapJSON(&swaps[idx]))
+	}
+	return out
+}
+
+func newArbitrageJSON(arb *types.Arbitrage) arbitrageJSON {
+	a := arbitrageJSON{
+		Type:          string(arb.Type),
+		TxHash:        arb.TxHash.Hex(),
+		BlockNumber:   arb.BlockNumber,
+		Arbitrageur:   arb.Arbitrageur.Hex(),
+		Path:          newSwapsJSON(arb.Path),
+		TokenStart:    arb.TokenStart.Hex(),
+		TokenEnd:      arb.TokenEnd.Hex(),
+		AmountIn:      decimal(arb.AmountIn),
+		AmountOut:     decimal(arb.AmountOut),
+		Profit:        decimal(arb.Profit),
+		ProfitToken:   arb.ProfitToken.Hex(),
+		GasUsed:       arb.GasUsed,
+		GasPrice:      optionalDecimal(arb.GasPrice),
+		BaseFee:       optionalDecimal(arb.BaseFee),
+		BurnedFee:     optionalDecimal(arb.BurnedFee),
+		PriorityFee:   optionalDecimal(arb.PriorityFee),
+		BlobFee:       optionalDecimal(arb.BlobFee),
+		NetProfitWei:  optionalDecimal(arb.NetProfitWei),
+		ProfitETHWei:  optionalDecimal(arb.ProfitETH),
+		ProfitUSD:     arb.ProfitUSD,
+		FlashLoanFees: optionalDecimal(arb.FlashLoanFees),
+	}
+	for _, loan := range arb.FlashLoans {
+		a.FlashLoans = append(a.FlashLoans, flashLoanJSON{
+			Protocol: loan.Protocol,
+			Lender:   loan.Lender.Hex(),
+			Borrower: loan.Borrower.Hex(),
+			Token:    loan.Token.Hex(),
+			Amount:   decimal(loan.Amount),
+			Fee:      decimal(loan.Fee),
+		})
+	}
+	return a
+}
+
+func newArbitragesJSON(arbs []types.Arbitrage) []arbitrageJSON {
+	out := make([]arbitrageJSON, 0, len(arbs))
+	for idx := range arbs {
+		out = append(out, newArbitrageJSON(&arbs[idx]))
+	}
+	return out
+}
+
+func newSandwichesJSON(sandwiches []types.Sandwich) []sandwichJSON {
+	out := make([]sandwichJSON, 0, len(sandwiches))
+	for idx := range sandwiches {
+		sw := &sandwiches[idx]
+		s := sandwichJSON{
+			BlockNumber: sw.BlockNumber,
+			Attacker:    sw.Attacker.Hex(),
+			Pool:        sw.Pool.Hex(),
+			Frontrun:    newSwapJSON(&sw.Frontrun),
+			Victims:     newSwapsJSON(sw.Victims),
+			Backrun:     newSwapJSON(&sw.Backrun),
+			ProfitToken: sw.ProfitToken.Hex(),
+			Profit:      decimal(sw.Profit),
+			VictimLoss:  optionalDecimal(sw.VictimLoss),
+		}
+		if sw.PoolID != (common.Hash{}) {
+			s.PoolID = sw.PoolID.Hex()
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func newLiquidationsJSON(liquidations []types.Liquidation) []liquidationJSON {
+	out := make([]liquidationJSON, 0, len(liquidations))
+	for idx := range liquidations {
+		liq := &liquidations[idx]
+		out = append(out, liquidationJSON{
+			TxHash:           liq.TxHash.Hex(),
+			BlockNumber:      liq.BlockNumber,
+			LogIndex:         liq.LogIndex,
+			Protocol:         liq.Protocol,
+			Liquidator:       liq.Liquidator.Hex(),
+			Borrower:         liq.Borrower.Hex(),
+			Absorbed:         liq.Absorbed,
+			CollateralAsset:  liq.CollateralAsset.Hex(),
+			CollateralAmount: decimal(liq.CollateralAmount),
+			DebtAsset:        liq.DebtAsset.Hex(),
+			DebtRepaid:       decimal(liq.DebtRepaid),
+			Swaps:            newSwapsJSON(liq.Swaps),
+			Profit:           optionalDecimal(liq.Profit),
+			ProfitToken:      liq.ProfitToken.Hex(),
+		})
+	}
+	return out
+}
+
+func newBlockJSON(block *types.BlockResult) blockJSON {
+	return blockJSON{
+		Number:         block.Number,
+		Hash:           block.Hash.Hex(),
+		ParentHash:     block.ParentHash.Hex(),
+		ProcessingTime: block.ProcessingTime.String(),
+		Swaps:          newSwapsJSON(block.Swaps),
+		Arbitrages:     newArbitragesJSON(block.Arbitrages),
+		Sandwiches:     newSandwichesJSON(block.Sandwiches),
+		Liquidations:   newLiquidationsJSON(block.Liquidations),
+	}
+}
+
+func newStatsJSON(stats output.Stats) statsJSON {
+	return statsJSON{
+		BlocksProcessed:   stats.BlocksProcessed,
+		SwapsDetected:     stats.SwapsDetected,
+		ArbitragesFound:   stats.ArbitragesFound,
+		SandwichesFound:   stats.SandwichesFound,
+		LiquidationsFound: stats.LiquidationsFound,
+		FlashLoanArbs:     stats.FlashLoanArbs,
+		ReorgsDetected:    stats.ReorgsDetected,
+		ResultsRetracted:  stats.ResultsRetracted,
+		UnpricedArbs:      stats.UnpricedArbs,
+		TotalProfitWei:    decimal(stats.TotalProfitWei),
+		TotalNetProfit:    decimal(stats.TotalNetProfit),
+		TotalProfitUSD:    stats.TotalProfitUSD,
+		StartTime:         stats.StartTime.UTC().Format(time.RFC3339),
+		Uptime:            time.Since(stats.StartTime).Round(time.Second).String(),
+	}
+}
+
+// decimal formats a big integer, treating nil as zero
+func decimal(v *big.Int) string {
+	if v == nil {
+		return "0"
+	}
+	return v.String()
+}
+
+// optionalDecimal formats a big integer, keeping nil as JSON null
+func optionalDecimal(v *big.Int) *string {
+	if v == nil {
+		return nil
+	}
+	s := v.String()
+	return &s
+}
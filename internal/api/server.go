@@ -0,0 +1,200 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog/log"
+
+	"github.com/devlongs/mev-inspector/internal/output"
+)
+
+// Server serves recent inspection results over HTTP
+type Server struct {
+	addr   string
+	buffer *Buffer
+	stats  func() output.Stats
+	mux    *http.ServeMux
+}
+
+// NewServer creates an API server for the results in buffer
+func NewServer(addr string, buffer *Buffer, stats func() output.Stats) *Server {
+	s := &Server{
+		addr:   addr,
+		buffer: buffer,
+		stats:  stats,
+		mux:    http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/arbitrages", s.handleArbitrages)
+	s.mux.HandleFunc("/blocks/", s.handleBlock)
+	s.mux.HandleFunc("/tx/", s.handleTx)
+	s.mux.HandleFunc("/stats", s.handleStats)
+
+	return s
+}
+
+// Handle registers an additional handler on the server
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Run serves requests until ctx is cancelled
+func (s *Server) Run(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.addr,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	log.Info().Str("addr", s.addr).Msg("API server listening")
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("API server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to shut down API server: %w", err)
+	}
+	return nil
+}
+
+// handleArbitrages serves GET /arbitrages?from_block=&to_block=&arbitrageur=&token=
+func (s *Server) handleArbitrages(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	query := r.URL.Query()
+	var filter ArbitrageFilter
+	var err error
+
+	if v := query.Get("from_block"); v != "" {
+		if filter.FromBlock, err = strconv.ParseUint(v, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid from_block")
+			return
+		}
+	}
+	if v := query.Get("to_block"); v != "" {
+		if filter.ToBlock, err = strconv.ParseUint(v, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid to_block")
+			return
+		}
+	}
+	if v := query.Get("arbitrageur"); v != "" {
+		if !common.IsHexAddress(v) {
+			writeError(w, http.StatusBadRequest, "invalid arbitrageur")
+			return
+		}
+		filter.Arbitrageur = common.HexToAddress(v)
+	}
+	if v := query.Get("token"); v != "" {
+		if !common.IsHexAddress(v) {
+			writeError(w, http.StatusBadRequest, "invalid token")
+			return
+		}
+		filter.Token = common.HexToAddress(v)
+	}
+
+	writeJSON(w, http.StatusOK, newArbitragesJSON(s.buffer.Arbitrages(filter)))
+}
+
+// handleBlock serves GET /blocks/{n}
+func (s *Server) handleBlock(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	number, err := strconv.ParseUint(strings.TrimPrefix(r.URL.Path, "/blocks/"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid block number")
+		return
+	}
+
+	block, ok := s.buffer.Block(number)
+	if !ok {
+		writeError(w, http.StatusNotFound, "block not in buffer")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newBlockJSON(block))
+}
+
+// handleTx serves GET /tx/{hash}
+func (s *Server) handleTx(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	raw := strings.TrimPrefix(r.URL.Path, "/tx/")
+	if len(strings.TrimPrefix(raw, "0x")) != 64 {
+		writeError(w, http.StatusBadRequest, "invalid transaction hash")
+		return
+	}
+	hash := common.HexToHash(raw)
+
+	result, ok := s.buffer.Tx(hash)
+	if !ok {
+		writeError(w, http.StatusNotFound, "transaction not in buffer")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, txJSON{
+		TxHash:       hash.Hex(),
+		BlockNumber:  result.BlockNumber,
+		Swaps:        newSwapsJSON(result.Swaps),
+		Arbitrages:   newArbitragesJSON(result.Arbitrages),
+		Sandwiches:   newSandwichesJSON(result.Sandwiches),
+		Liquidations: newLiquidationsJSON(result.Liquidations),
+	})
+}
+
+// handleStats serves GET /stats
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newStatsJSON(s.stats()))
+}
+
+// allowGet rejects requests that aren't GET
+func allowGet(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return false
+	}
+	return true
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Debug().Err(err).Msg("Failed to write API response")
+	}
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorJSON{Error: message})
+}
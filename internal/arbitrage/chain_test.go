@@ -0,0 +1,470 @@
+package arbitrage_test
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/devlongs/mev-inspector/internal/dex/uniswapv2"
+	"github.com/devlongs/mev-inspector/internal/dex/uniswapv3"
+	"github.com/devlongs/mev-inspector/internal/flashloan"
+)
+
+// Mainnet contracts the constructed blocks trade against, so decoded swaps
+// carry real pool and token addresses
+var (
+	usdc          = common.HexToAddress("0xA0b86991c6218b36c1D19D4a2e9Eb0cE3606eB48")
+	weth          = common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	usdcWETHV2    = common.HexToAddress("0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc")
+	usdcWETHV3    = common.HexToAddress("0x88e6A0c2dDD26FEEb64F039a2c41296FcB3f5640")
+	v2Router      = common.HexToAddress("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D")
+	balancerVault = common.HexToAddress("0xBA12222222228d8Ba445958a75a0704d566BF2C8")
+	multicall3    = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+)
+
+// Made-up searcher contracts
+var (
+	searcherA = common.HexToAddress("0x00000000000000000000000000000000000A4B01")
+	searcherB = common.HexToAddress("0x00000000000000000000000000000000000A4B02")
+)
+
+// multicall3ABI covers aggregate3((address,bool,bytes)[]) returns ((bool,bytes)[])
+const multicall3ABI = `[{"inputs":[{"components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}],"name":"calls","type":"tuple[]"}],"name":"aggregate3","outputs":[{"components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}],"name":"returnData","type":"tuple[]"}],"stateMutability":"payable","type":"function"}]`
+
+// poolMeta is what a pool's token0(), token1() and fee() return
+type poolMeta struct {
+	token0, token1 common.Address
+	fee            uint32 // 0 for V2 pairs, which have no fee()
+}
+
+var constructedPools = map[common.Address]poolMeta{
+	usdcWETHV2: {token0: usdc, token1: weth},
+	usdcWETHV3: {token0: usdc, token1: weth, fee: 500},
+}
+
+// Fee market of the constructed blocks: every transaction is an EIP-1559
+// transaction with the same fee cap, and pays its tip on top of the base fee
+var (
+	constructedBaseFee = gwei(20)
+	constructedFeeCap  = gwei(40)
+)
+
+// chainTx is a transaction of a constructed block and the logs it emits
+type chainTx struct {
+	from    string // Seed of the sender's key
+	to      common.Address
+	tip     int64 // gwei
+	gasUsed uint64
+	logs    []*ethtypes.Log
+}
+
+// constructedChain is a block assembled for a test rather than taken from a
+// chain. It is served over JSON-RPC so its fixture is recorded with
+// rpctest.Recorder like one taken from a node.
+type constructedChain struct {
+	chainID  *big.Int
+	header   *ethtypes.Header
+	txs      []*ethtypes.Transaction
+	senders  []common.Address
+	receipts []*ethtypes.Receipt
+}
+
+// usdcWETHChain is block 18000000 of a constructed chain with two
+// arbitrages between the USDC/WETH pools and a plain router swap
+func usdcWETHChain(t testing.TB) *constructedChain {
+	return newConstructedChain(t, 18000000, []chainTx{
+		{
+			// WETH -> USDC on V3, USDC -> WETH on V2
+			from: "searcher a", to: searcherA, tip: 1, gasUsed: 182000,
+			logs: []*ethtypes.Log{
+				v3Swap(searcherA, usdcWETHV2, big.NewInt(-16420000000), ether(10, 0),
+					bigInt("3207784692195812436281469395911385"), bigInt("17364214837260372981"), 201170),
+				v2Swap(searcherA, searcherA, big.NewInt(16420000000), new(big.Int), new(big.Int), ether(10, 48)),
+			},
+		},
+		{
+			// USDC -> WETH through the router
+			from: "trader", to: v2Router, tip: 1, gasUsed: 120000,
+			logs: []*ethtypes.Log{
+				v2Swap(v2Router, keyAddress(t, "trader"), big.NewInt(5000000000), new(big.Int), new(big.Int), ether(3, 40)),
+			},
+		},
+		{
+			// Flash loan of 100 WETH, WETH -> USDC on V2, USDC -> WETH on V3
+			from: "searcher b", to: searcherB, tip: 3, gasUsed: 246000,
+			logs: []*ethtypes.Log{
+				v2Swap(searcherB, usdcWETHV3, new(big.Int), ether(100, 0), big.NewInt(164500000000), new(big.Int)),
+				v3Swap(searcherB, searcherB, big.NewInt(164500000000), new(big.Int).Neg(ether(100, 120)),
+					bigInt("3207812214977106617451829287140522"), bigInt("17364214837260372981"), 201171),
+				{
+					Address: balancerVault,
+					Topics:  []common.Hash{flashloan.BalancerFlashLoanSignature, addressTopic(searcherB), addressTopic(weth)},
+					Data:    words(ether(100, 0), new(big.Int)),
+				},
+			},
+		},
+	})
+}
+
+// newConstructedChain signs the transactions and builds their receipts and
+// the block header
+func newConstructedChain(t testing.TB, number uint64, txs []chainTx) *constructedChain {
+	t.Helper()
+
+	c := &constructedChain{chainID: big.NewInt(1)}
+	signer := ethtypes.LatestSignerForChainID(c.chainID)
+	nonces := make(map[string]uint64)
+
+	var cumulativeGas uint64
+	var logIndex uint
+	for idx, spec := range txs {
+		key := mustKey(t, spec.from)
+		tx, err := ethtypes.SignTx(ethtypes.NewTx(&ethtypes.DynamicFeeTx{
+			ChainID:   c.chainID,
+			Nonce:     nonces[spec.from],
+			GasTipCap: gwei(spec.tip),
+			GasFeeCap: constructedFeeCap,
+			Gas:       spec.gasUsed + 50000,
+			To:        &spec.to,
+		}), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		nonces[spec.from]++
+
+		cumulativeGas += spec.gasUsed
+		for _, l := range spec.logs {
+			l.BlockNumber = number
+			l.TxHash = tx.Hash()
+			l.TxIndex = uint(idx)
+			l.Index = logIndex
+			logIndex++
+		}
+		receipt := &ethtypes.Receipt{
+			Type:              tx.Type(),
+			Status:            ethtypes.ReceiptStatusSuccessful,
+			CumulativeGasUsed: cumulativeGas,
+			Logs:              spec.logs,
+			TxHash:            tx.Hash(),
+			GasUsed:           spec.gasUsed,
+			EffectiveGasPrice: new(big.Int).Add(constructedBaseFee, tx.GasTipCap()),
+			BlockNumber:       new(big.Int).SetUint64(number),
+			TransactionIndex:  uint(idx),
+		}
+		receipt.Bloom = ethtypes.CreateBloom(ethtypes.Receipts{receipt})
+
+		c.txs = append(c.txs, tx)
+		c.senders = append(c.senders, crypto.PubkeyToAddress(key.PublicKey))
+		c.receipts = append(c.receipts, receipt)
+	}
+
+	// Nothing reads the state, transaction or receipt roots, so they are
+	// left empty rather than computed
+	c.header = &ethtypes.Header{
+		ParentHash:  crypto.Keccak256Hash([]byte("constructed parent")),
+		UncleHash:   ethtypes.EmptyUncleHash,
+		Root:        ethtypes.EmptyRootHash,
+		TxHash:      ethtypes.EmptyRootHash,
+		ReceiptHash: ethtypes.EmptyRootHash,
+		Bloom:       ethtypes.CreateBloom(c.receipts),
+		Difficulty:  new(big.Int),
+		Number:      new(big.Int).SetUint64(number),
+		GasLimit:    30000000,
+		GasUsed:     cumulativeGas,
+		Time:        1693066895,
+		Extra:       []byte("constructed"),
+		BaseFee:     constructedBaseFee,
+		// Post-Shanghai headers commit to the block's (here empty) withdrawals
+		WithdrawalsHash: &ethtypes.EmptyWithdrawalsHash,
+	}
+
+	hash := c.header.Hash()
+	for _, receipt := range c.receipts {
+		receipt.BlockHash = hash
+		for _, l := range receipt.Logs {
+			l.BlockHash = hash
+		}
+	}
+
+	return c
+}
+
+// server returns a JSON-RPC server for the chain
+func (c *constructedChain) server(t testing.TB) http.Handler {
+	t.Helper()
+
+	mc, err := abi.JSON(strings.NewReader(multicall3ABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &chainService{chain: c, multicall: mc.Methods["aggregate3"]}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+	return server
+}
+
+// chainService implements the eth_ methods the inspector uses
+type chainService struct {
+	chain     *constructedChain
+	multicall abi.Method
+}
+
+func (s *chainService) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(s.chain.chainID)
+}
+
+func (s *chainService) GetBlockByNumber(number rpc.BlockNumber, full bool) (map[string]interface{}, error) {
+	if full {
+		return nil, errors.New("full blocks are not supported")
+	}
+	if number.Int64() != s.chain.header.Number.Int64() {
+		return nil, nil
+	}
+
+	block, err := toMap(s.chain.header)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]common.Hash, len(s.chain.txs))
+	for idx, tx := range s.chain.txs {
+		hashes[idx] = tx.Hash()
+	}
+	block["transactions"] = hashes
+	block["uncles"] = []common.Hash{}
+	return block, nil
+}
+
+// logFilter is the filter object of eth_getLogs
+type logFilter struct {
+	FromBlock hexutil.Uint64   `json:"fromBlock"`
+	ToBlock   hexutil.Uint64   `json:"toBlock"`
+	Addresses []common.Address `json:"address"`
+	Topics    [][]common.Hash  `json:"topics"`
+}
+
+func (s *chainService) GetLogs(filter logFilter) ([]*ethtypes.Log, error) {
+	logs := []*ethtypes.Log{}
+	number := s.chain.header.Number.Uint64()
+	if number < uint64(filter.FromBlock) || number > uint64(filter.ToBlock) {
+		return logs, nil
+	}
+
+	for _, receipt := range s.chain.receipts {
+		for _, l := range receipt.Logs {
+			if filter.matches(l) {
+				logs = append(logs, l)
+			}
+		}
+	}
+	return logs, nil
+}
+
+func (f logFilter) matches(l *ethtypes.Log) bool {
+	if len(f.Addresses) > 0 && !contains(f.Addresses, l.Address) {
+		return false
+	}
+	for idx, topics := range f.Topics {
+		if len(topics) == 0 {
+			continue
+		}
+		if idx >= len(l.Topics) || !contains(topics, l.Topics[idx]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *chainService) GetTransactionByHash(hash common.Hash) (map[string]interface{}, error) {
+	for idx, tx := range s.chain.txs {
+		if tx.Hash() != hash {
+			continue
+		}
+		fields, err := toMap(tx)
+		if err != nil {
+			return nil, err
+		}
+		fields["blockHash"] = s.chain.header.Hash()
+		fields["blockNumber"] = (*hexutil.Big)(s.chain.header.Number)
+		fields["transactionIndex"] = hexutil.Uint64(idx)
+		fields["from"] = s.chain.senders[idx]
+		fields["gasPrice"] = (*hexutil.Big)(s.chain.receipts[idx].EffectiveGasPrice)
+		return fields, nil
+	}
+	return nil, nil
+}
+
+func (s *chainService) GetTransactionReceipt(hash common.Hash) (*ethtypes.Receipt, error) {
+	for _, receipt := range s.chain.receipts {
+		if receipt.TxHash == hash {
+			return receipt, nil
+		}
+	}
+	return nil, nil
+}
+
+// callArgs is the call object of eth_call
+type callArgs struct {
+	To    *common.Address `json:"to"`
+	Input hexutil.Bytes   `json:"input"`
+}
+
+// Call answers pool metadata calls, directly or through Multicall3
+func (s *chainService) Call(args callArgs, block rpc.BlockNumber) (hexutil.Bytes, error) {
+	if args.To == nil {
+		return nil, errors.New("contract creation is not supported")
+	}
+	if *args.To != multicall3 {
+		return poolCall(*args.To, args.Input)
+	}
+
+	if len(args.Input) < 4 || !strings.EqualFold(hexutil.Encode(args.Input[:4]), hexutil.Encode(s.multicall.ID)) {
+		return nil, errors.New("execution reverted")
+	}
+	unpacked, err := s.multicall.Inputs.Unpack(args.Input[4:])
+	if err != nil {
+		return nil, err
+	}
+	var calls []struct {
+		Target       common.Address
+		AllowFailure bool
+		CallData     []byte
+	}
+	if err := s.multicall.Inputs.Copy(&calls, unpacked); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		Success    bool
+		ReturnData []byte
+	}
+	results := make([]result, len(calls))
+	for idx, call := range calls {
+		data, err := poolCall(call.Target, call.CallData)
+		if err != nil && !call.AllowFailure {
+			return nil, err
+		}
+		results[idx] = result{Success: err == nil, ReturnData: data}
+	}
+	return s.multicall.Outputs.Pack(results)
+}
+
+// poolCall answers token0(), token1() and fee() for the constructed pools
+// and reverts anything else
+func poolCall(target common.Address, input []byte) (hexutil.Bytes, error) {
+	pool, ok := constructedPools[target]
+	if !ok || len(input) < 4 {
+		return nil, errors.New("execution reverted")
+	}
+
+	switch hexutil.Encode(input[:4]) {
+	case "0x0dfe1681": // token0()
+		return common.LeftPadBytes(pool.token0.Bytes(), 32), nil
+	case "0xd21220a7": // token1()
+		return common.LeftPadBytes(pool.token1.Bytes(), 32), nil
+	case "0xddca3f43": // fee()
+		if pool.fee != 0 {
+			return words(big.NewInt(int64(pool.fee))), nil
+		}
+	}
+	return nil, errors.New("execution reverted")
+}
+
+// v2Swap is a Uniswap V2 Swap event of the USDC/WETH pair
+func v2Swap(sender, to common.Address, amount0In, amount1In, amount0Out, amount1Out *big.Int) *ethtypes.Log {
+	return &ethtypes.Log{
+		Address: usdcWETHV2,
+		Topics:  []common.Hash{uniswapv2.SwapEventSignature, addressTopic(sender), addressTopic(to)},
+		Data:    words(amount0In, amount1In, amount0Out, amount1Out),
+	}
+}
+
+// v3Swap is a Uniswap V3 Swap event of the 0.05% USDC/WETH pool
+func v3Swap(sender, recipient common.Address, amount0, amount1, sqrtPriceX96, liquidity *big.Int, tick int64) *ethtypes.Log {
+	return &ethtypes.Log{
+		Address: usdcWETHV3,
+		Topics:  []common.Hash{uniswapv3.SwapEventSignature, addressTopic(sender), addressTopic(recipient)},
+		Data:    words(amount0, amount1, sqrtPriceX96, liquidity, big.NewInt(tick)),
+	}
+}
+
+// words ABI-encodes values as 32-byte two's complement words
+func words(values ...*big.Int) []byte {
+	var data []byte
+	for _, v := range values {
+		data = append(data, math.U256Bytes(new(big.Int).Set(v))...)
+	}
+	return data
+}
+
+func addressTopic(addr common.Address) common.Hash {
+	return common.BytesToHash(addr.Bytes())
+}
+
+// ether returns whole ether plus thousandths, e.g. ether(10, 48) is 10.048
+func ether(whole, thousandths int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(whole*1000+thousandths), big.NewInt(1e15))
+}
+
+func gwei(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e9))
+}
+
+func bigInt(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic(fmt.Sprintf("invalid integer %q", s))
+	}
+	return v
+}
+
+// mustKey derives a fixed private key from seed, so the transactions and
+// their hashes are the same every time the chain is built
+func mustKey(t testing.TB, seed string) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := crypto.ToECDSA(crypto.Keccak256([]byte(seed)))
+	if err != nil {
+		t.Fatalf("failed to derive key: %v", err)
+	}
+	return key
+}
+
+func keyAddress(t testing.TB, seed string) common.Address {
+	return crypto.PubkeyToAddress(mustKey(t, seed).PublicKey)
+}
+
+// toMap converts v to its JSON object so fields can be added
+func toMap(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func contains[T comparable](list []T, v T) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
@@ -5,26 +5,37 @@ import (
 	"fmt"
 	"math/big"
 	"sort"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/rs/zerolog/log"
 
 	"github.com/devlongs/mev-inspector/internal/eth"
+	"github.com/devlongs/mev-inspector/internal/flashloan"
 	"github.com/devlongs/mev-inspector/pkg/types"
 )
 
 // WETH address on mainnet
 var WETH = common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
 
+// Blocks whose base fee is kept, counting back from the newest one seen
+const baseFeeCacheSize = 256
+
 // Detector detects arbitrage opportunities from swap events
 type Detector struct {
-	client *eth.Client
+	client     eth.Reader
+	flashLoans *flashloan.Decoder
+	baseFees   map[uint64]*big.Int // Nil for blocks before London
+	baseFeesMu sync.RWMutex
 }
 
 // NewDetector creates a new arbitrage detector
-func NewDetector(client *eth.Client) *Detector {
+func NewDetector(client eth.Reader) *Detector {
 	return &Detector{
-		client: client,
+		client:     client,
+		flashLoans: flashloan.NewDecoder(client),
+		baseFees:   make(map[uint64]*big.Int),
 	}
 }
 
@@ -46,37 +57,200 @@ func (d *Detector) DetectArbitrage(ctx context.Context, txHash common.Hash, swap
 	cyclicArb := d.detectCyclicArbitrage(swaps)
 	if cyclicArb != nil {
 		foundCyclic = true
-		// Fetch transaction details for gas info
-		if receipt, err := d.client.TransactionReceipt(ctx, txHash); err == nil {
-			cyclicArb.GasUsed = receipt.GasUsed
-			if tx, _, err := d.client.GetTransaction(ctx, txHash); err == nil {
-				cyclicArb.GasPrice = tx.GasPrice()
-				gasCost := new(big.Int).Mul(big.NewInt(int64(cyclicArb.GasUsed)), cyclicArb.GasPrice)
-				cyclicArb.NetProfitWei = new(big.Int).Sub(cyclicArb.Profit, gasCost)
-			}
-		}
 		arbitrages = append(arbitrages, *cyclicArb)
 	}
 
 	// Only detect cross-DEX if no cyclic arbitrage found (avoid duplicates)
 	if !foundCyclic {
-		crossDexArbs := d.detectCrossDEXArbitrage(swaps)
-		for _, arb := range crossDexArbs {
-			if receipt, err := d.client.TransactionReceipt(ctx, txHash); err == nil {
-				arb.GasUsed = receipt.GasUsed
-				if tx, _, err := d.client.GetTransaction(ctx, txHash); err == nil {
-					arb.GasPrice = tx.GasPrice()
-					gasCost := new(big.Int).Mul(big.NewInt(int64(arb.GasUsed)), arb.GasPrice)
-					arb.NetProfitWei = new(big.Int).Sub(arb.Profit, gasCost)
-				}
-			}
-			arbitrages = append(arbitrages, arb)
+		arbitrages = append(arbitrages, d.detectCrossDEXArbitrage(swaps)...)
+	}
+
+	if len(arbitrages) == 0 {
+		return nil, nil
+	}
+
+	// Fetch the transaction once for every arbitrage in it
+	tx, receipt, err := d.client.TransactionAndReceipt(ctx, txHash)
+	if err != nil {
+		return arbitrages, nil
+	}
+
+	// Without the base fee the gas cost can't be split, but it's still known
+	baseFee, feeErr := d.baseFee(ctx, receipt.BlockNumber)
+	if feeErr != nil {
+		log.Debug().Err(feeErr).Str("txHash", txHash.Hex()).Msg("Failed to fetch base fee")
+	}
+
+	for idx := range arbitrages {
+		d.enrichArbitrage(ctx, &arbitrages[idx], tx, receipt)
+		if feeErr == nil {
+			arb := &arbitrages[idx]
+			arb.BaseFee = baseFee
+			arb.BurnedFee, arb.PriorityFee = SplitGasCost(arb.GasUsed, arb.GasPrice, baseFee)
 		}
 	}
 
 	return arbitrages, nil
 }
 
+// enrichArbitrage uses the transaction and its receipt to fill in gas usage,
+// flash loans and, for WETH profits with fees only in WETH, net profit.
+// Anything else needs a price and is netted by the pricing package.
+func (d *Detector) enrichArbitrage(ctx context.Context, arb *types.Arbitrage, tx *ethtypes.Transaction, receipt *ethtypes.Receipt) {
+	arb.GasUsed = receipt.GasUsed
+
+	// Flash loan fees reduce net profit. Those paid in other tokens need a
+	// price and are added by the pricing package.
+	arb.FlashLoans = d.flashLoans.DecodeFlashLoans(ctx, receipt.Logs)
+	arb.FlashLoanFees = flashloan.FeesIn(arb.FlashLoans, arb.ProfitToken)
+
+	// For dynamic fee transactions tx.GasPrice() is the fee cap; the receipt
+	// has what was actually paid per gas
+	arb.GasPrice = receipt.EffectiveGasPrice
+	if arb.GasPrice == nil {
+		arb.GasPrice = tx.GasPrice()
+	}
+
+	arb.BlobFee = BlobFee(tx, receipt)
+
+	if arb.ProfitToken == WETH && !flashloan.HasFeesOutside(arb.FlashLoans, WETH) {
+		arb.NetProfitWei = new(big.Int).Sub(arb.Profit, arb.FlashLoanFees)
+		arb.NetProfitWei.Sub(arb.NetProfitWei, GasCost(arb))
+	}
+	if arb.Arbitrageur == (common.Address{}) {
+		arb.Arbitrageur = d.txSender(tx)
+	}
+}
+
+// SplitGasCost divides the execution gas cost of a transaction into the base
+// fee, which is burned, and the priority fee, which goes to the block's
+// proposer. Before London there was no base fee and the miner got it all.
+func SplitGasCost(gasUsed uint64, gasPrice, baseFee *big.Int) (burned, priority *big.Int) {
+	if gasPrice == nil {
+		return nil, nil
+	}
+
+	gas := new(big.Int).SetUint64(gasUsed)
+	cost := new(big.Int).Mul(gas, gasPrice)
+	if baseFee == nil {
+		return new(big.Int), cost
+	}
+
+	// A price below the base fee can't be included; clamp rather than report
+	// a negative tip for an inconsistent receipt
+	burned = new(big.Int).Mul(gas, baseFee)
+	if burned.Cmp(cost) > 0 {
+		burned.Set(cost)
+	}
+	return burned, cost.Sub(cost, burned)
+}
+
+// BlobFee returns the blob gas cost of a type-3 transaction, which is burned
+// in full, or nil for other transactions
+func BlobFee(tx *ethtypes.Transaction, receipt *ethtypes.Receipt) *big.Int {
+	if tx.Type() != ethtypes.BlobTxType || receipt.BlobGasPrice == nil {
+		return nil
+	}
+	return new(big.Int).Mul(new(big.Int).SetUint64(receipt.BlobGasUsed), receipt.BlobGasPrice)
+}
+
+// baseFee returns the base fee of a block, or nil for blocks before London
+func (d *Detector) baseFee(ctx context.Context, number *big.Int) (*big.Int, error) {
+	if number == nil {
+		return nil, fmt.Errorf("receipt has no block number")
+	}
+	block := number.Uint64()
+
+	d.baseFeesMu.RLock()
+	fee, ok := d.baseFees[block]
+	d.baseFeesMu.RUnlock()
+	if ok {
+		return fee, nil
+	}
+
+	header, err := d.client.HeaderByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	d.baseFeesMu.Lock()
+	d.baseFees[block] = header.BaseFee
+	if len(d.baseFees) > baseFeeCacheSize {
+		for b := range d.baseFees {
+			if b+baseFeeCacheSize < block {
+				delete(d.baseFees, b)
+			}
+		}
+	}
+	d.baseFeesMu.Unlock()
+
+	return header.BaseFee, nil
+}
+
+// GasCost returns the ETH paid for the gas of an arbitrage's transaction,
+// including blob gas
+func GasCost(arb *types.Arbitrage) *big.Int {
+	cost := new(big.Int)
+	if arb.GasPrice != nil {
+		cost.Mul(new(big.Int).SetUint64(arb.GasUsed), arb.GasPrice)
+	}
+	if arb.BlobFee != nil {
+		cost.Add(cost, arb.BlobFee)
+	}
+	return cost
+}
+
+// TokenFlow is the direction of a single swap
+type TokenFlow struct {
+	TokenIn   common.Address
+	TokenOut  common.Address
+	AmountIn  *big.Int
+	AmountOut *big.Int
+}
+
+// FlowOf determines which token went into a swap and which came out. It
+// returns false when the direction can't be determined from the amounts.
+// Every detector uses it, so they agree on which way a swap went.
+func FlowOf(swap *types.Swap) (TokenFlow, bool) {
+	var flow TokenFlow
+
+	// Determine token in/out based on amounts
+	if swap.Amount0In.Sign() > 0 && swap.Amount1Out.Sign() > 0 {
+		flow = TokenFlow{
+			TokenIn:   swap.Token0,
+			TokenOut:  swap.Token1,
+			AmountIn:  swap.Amount0In,
+			AmountOut: swap.Amount1Out,
+		}
+	} else if swap.Amount1In.Sign() > 0 && swap.Amount0Out.Sign() > 0 {
+		flow = TokenFlow{
+			TokenIn:   swap.Token1,
+			TokenOut:  swap.Token0,
+			AmountIn:  swap.Amount1In,
+			AmountOut: swap.Amount0Out,
+		}
+	} else {
+		// Handle V3 style where both amounts can be set
+		if swap.Amount0In.Sign() > 0 {
+			flow.TokenIn = swap.Token0
+			flow.AmountIn = swap.Amount0In
+		} else if swap.Amount1In.Sign() > 0 {
+			flow.TokenIn = swap.Token1
+			flow.AmountIn = swap.Amount1In
+		}
+		if swap.Amount0Out.Sign() > 0 {
+			flow.TokenOut = swap.Token0
+			flow.AmountOut = swap.Amount0Out
+		} else if swap.Amount1Out.Sign() > 0 {
+			flow.TokenOut = swap.Token1
+			flow.AmountOut = swap.Amount1Out
+		}
+	}
+
+	ok := flow.TokenIn != (common.Address{}) && flow.TokenOut != (common.Address{})
+	return flow, ok && flow.TokenIn != flow.TokenOut
+}
+
 // detectCyclicArbitrage detects A -> B -> C -> A style arbitrage
 func (d *Detector) detectCyclicArbitrage(swaps []types.Swap) *types.Arbitrage {
 	if len(swaps) < 2 {
@@ -85,52 +259,10 @@ func (d *Detector) detectCyclicArbitrage(swaps []types.Swap) *types.Arbitrage {
 
 	// Build a token flow graph
 	// Track: which token goes in, which comes out for each swap
-	type tokenFlow struct {
-		tokenIn    common.Address
-		tokenOut   common.Address
-		amountIn   *big.Int
-		amountOut  *big.Int
-	}
-
-	flows := make([]tokenFlow, 0, len(swaps))
+	flows := make([]TokenFlow, 0, len(swaps))
 
 	for _, swap := range swaps {
-		var flow tokenFlow
-
-		// Determine token in/out based on amounts
-		if swap.Amount0In.Sign() > 0 && swap.Amount1Out.Sign() > 0 {
-			flow = tokenFlow{
-				tokenIn:   swap.Token0,
-				tokenOut:  swap.Token1,
-				amountIn:  swap.Amount0In,
-				amountOut: swap.Amount1Out,
-			}
-		} else if swap.Amount1In.Sign() > 0 && swap.Amount0Out.Sign() > 0 {
-			flow = tokenFlow{
-				tokenIn:   swap.Token1,
-				tokenOut:  swap.Token0,
-				amountIn:  swap.Amount1In,
-				amountOut: swap.Amount0Out,
-			}
-		} else {
-			// Handle V3 style where both amounts can be set
-			if swap.Amount0In.Sign() > 0 {
-				flow.tokenIn = swap.Token0
-				flow.amountIn = swap.Amount0In
-			} else if swap.Amount1In.Sign() > 0 {
-				flow.tokenIn = swap.Token1
-				flow.amountIn = swap.Amount1In
-			}
-			if swap.Amount0Out.Sign() > 0 {
-				flow.tokenOut = swap.Token0
-				flow.amountOut = swap.Amount0Out
-			} else if swap.Amount1Out.Sign() > 0 {
-				flow.tokenOut = swap.Token1
-				flow.amountOut = swap.Amount1Out
-			}
-		}
-
-		if flow.tokenIn != (common.Address{}) && flow.tokenOut != (common.Address{}) {
+		if flow, ok := FlowOf(&swap); ok {
 			flows = append(flows, flow)
 		}
 	}
@@ -140,8 +272,8 @@ func (d *Detector) detectCyclicArbitrage(swaps []types.Swap) *types.Arbitrage {
 	}
 
 	// Check if first token in == last token out (cyclic)
-	firstToken := flows[0].tokenIn
-	lastToken := flows[len(flows)-1].tokenOut
+	firstToken := flows[0].TokenIn
+	lastToken := flows[len(flows)-1].TokenOut
 
 	if firstToken != lastToken {
 		return nil
@@ -149,14 +281,14 @@ func (d *Detector) detectCyclicArbitrage(swaps []types.Swap) *types.Arbitrage {
 
 	// Check if the path is connected (output of swap N feeds into swap N+1)
 	for i := 0; i < len(flows)-1; i++ {
-		if flows[i].tokenOut != flows[i+1].tokenIn {
+		if flows[i].TokenOut != flows[i+1].TokenIn {
 			return nil
 		}
 	}
 
 	// Calculate profit
-	amountIn := flows[0].amountIn
-	amountOut := flows[len(flows)-1].amountOut
+	amountIn := flows[0].AmountIn
+	amountOut := flows[len(flows)-1].AmountOut
 
 	if amountOut.Cmp(amountIn) <= 0 {
 		return nil // No profit
@@ -175,6 +307,7 @@ func (d *Detector) detectCyclicArbitrage(swaps []types.Swap) *types.Arbitrage {
 		Msg("Detected cyclic arbitrage")
 
 	return &types.Arbitrage{
+		Type:        types.ArbitrageTypeCyclic,
 		TxHash:      swaps[0].TxHash,
 		BlockNumber: swaps[0].BlockNumber,
 		Arbitrageur: arbitrageur,
@@ -217,10 +350,15 @@ func (d *Detector) detectCrossDEXArbitrage(swaps []types.Swap) []types.Arbitrage
 			continue
 		}
 
-		// Check if swaps are on different pools
-		pools := make(map[common.Address]bool)
+		// Check if swaps are on different pools (singleton DEXes share an
+		// address, so pools are keyed by address and pool ID)
+		type poolKey struct {
+			address common.Address
+			id      common.Hash
+		}
+		pools := make(map[poolKey]bool)
 		for _, swap := range swapsForPair {
-			pools[swap.Pool] = true
+			pools[poolKey{address: swap.Pool, id: swap.PoolID}] = true
 		}
 
 		if len(pools) < 2 {
@@ -248,7 +386,7 @@ func (d *Detector) detectCrossDEXArbitrage(swaps []types.Swap) []types.Arbitrage
 			}
 		}
 
-		if buySwap != nil && sellSwap != nil && buySwap.Pool != sellSwap.Pool {
+		if buySwap != nil && sellSwap != nil && (buySwap.Pool != sellSwap.Pool || buySwap.PoolID != sellSwap.PoolID) {
 			// Calculate profit in WETH
 			var wethIn, wethOut *big.Int
 
@@ -274,6 +412,7 @@ func (d *Detector) detectCrossDEXArbitrage(swaps []types.Swap) []types.Arbitrage
 					Msg("Detected cross-DEX arbitrage")
 
 				arbitrages = append(arbitrages, types.Arbitrage{
+					Type:        types.ArbitrageTypeCrossDEX,
 					TxHash:      buySwap.TxHash,
 					BlockNumber: buySwap.BlockNumber,
 					Arbitrageur: buySwap.Sender,
@@ -292,6 +431,16 @@ func (d *Detector) detectCrossDEXArbitrage(swaps []types.Swap) []types.Arbitrage
 	return arbitrages
 }
 
+// txSender recovers the transaction sender, used when the swap events
+// themselves don't identify the caller (e.g. Balancer Vault swaps)
+func (d *Detector) txSender(tx *ethtypes.Transaction) common.Address {
+	from, err := ethtypes.Sender(ethtypes.LatestSignerForChainID(d.client.ChainID()), tx)
+	if err != nil {
+		return common.Address{}
+	}
+	return from
+}
+
 // IsProfitable checks if an arbitrage is profitable after gas costs
 func (d *Detector) IsProfitable(arb *types.Arbitrage) bool {
 	if arb.NetProfitWei == nil {
@@ -0,0 +1,132 @@
+package arbitrage_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/devlongs/mev-inspector/internal/arbitrage"
+	"github.com/devlongs/mev-inspector/internal/config"
+	"github.com/devlongs/mev-inspector/internal/decoder"
+	"github.com/devlongs/mev-inspector/internal/eth"
+	"github.com/devlongs/mev-inspector/internal/eth/rpctest"
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// Blocks replayed from testdata/<name>.json and compared against
+// testdata/<name>.golden.json. Fixtures of constructed blocks are recorded
+// from chain when run with -update.
+var recordedBlocks = []struct {
+	name      string
+	protocols []string
+	from, to  uint64
+	chain     func(testing.TB) *constructedChain
+}{
+	{name: "constructed_usdc_weth", protocols: []string{"uniswap_v2", "uniswap_v3"}, from: 18000000, to: 18000000, chain: usdcWETHChain},
+}
+
+func TestDetectArbitrageRecordedBlocks(t *testing.T) {
+	for _, tc := range recordedBlocks {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			fixture := filepath.Join("testdata", tc.name+".json")
+			var transport http.RoundTripper
+			var url string
+			if tc.chain != nil && *update {
+				node := httptest.NewServer(tc.chain(t).server(t))
+				defer node.Close()
+				transport, url = rpctest.Record(t, fixture), node.URL
+			} else {
+				transport, url = rpctest.Transport(t, fixture)
+			}
+
+			client, err := eth.NewClient(config.RPCConfig{
+				URL:              url,
+				RetryAttempts:    1,
+				RequestTimeout:   30 * time.Second,
+				MaxBatchSize:     100,
+				MulticallAddress: "0xcA11bde05977b3631167028862bE2a173976CA11",
+			}, eth.WithTransport(transport))
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+			defer client.Close()
+
+			arbs := detectArbitrages(t, client, tc.protocols, tc.from, tc.to)
+
+			got, err := json.MarshalIndent(arbs, "", "  ")
+			if err != nil {
+				t.Fatalf("failed to encode arbitrages: %v", err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", tc.name+".golden.json")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatalf("failed to write golden file: %v", err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("arbitrages differ from %s (run with -update to accept):\n%s", golden, got)
+			}
+		})
+	}
+}
+
+// detectArbitrages runs the decoder and detector over a block range the way
+// the inspector pipeline does
+func detectArbitrages(t *testing.T, client eth.Reader, protocols []string, from, to uint64) []types.Arbitrage {
+	t.Helper()
+	ctx := context.Background()
+
+	dec, err := decoder.NewDecoder(client, protocols)
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+	detector := arbitrage.NewDetector(client)
+
+	logs, err := dec.GetAllSwapLogs(ctx, from, to)
+	if err != nil {
+		t.Fatalf("failed to get swap logs: %v", err)
+	}
+	dec.PrefetchPools(ctx, logs)
+
+	txLogs := dec.GroupSwapsByTransaction(logs)
+	var arbs []types.Arbitrage
+	seen := make(map[common.Hash]bool)
+	for _, l := range logs {
+		if seen[l.TxHash] {
+			continue
+		}
+		seen[l.TxHash] = true
+
+		swaps, err := dec.DecodeSwapsForTransaction(ctx, txLogs[l.TxHash])
+		if err != nil {
+			t.Fatalf("failed to decode swaps of %s: %v", l.TxHash.Hex(), err)
+		}
+		found, err := detector.DetectArbitrage(ctx, l.TxHash, swaps)
+		if err != nil {
+			t.Fatalf("failed to detect arbitrage in %s: %v", l.TxHash.Hex(), err)
+		}
+		arbs = append(arbs, found...)
+	}
+
+	return arbs
+}
@@ -0,0 +1,95 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Store persists the last fully processed block
+type Store interface {
+	// Load returns the last processed block, or ok=false if none was saved
+	Load() (block uint64, ok bool, err error)
+	// Save records block as the last fully processed block
+	Save(block uint64) error
+}
+
+// fileCheckpoint is the on-disk checkpoint format
+type fileCheckpoint struct {
+	LastBlock uint64    `json:"last_block"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FileStore is a Store backed by a JSON file that is replaced atomically
+type FileStore struct {
+	path string
+}
+
+// NewFileStore creates a file-backed checkpoint store
+func NewFileStore(path string) *FileStore {
+	return &FileStore{
+		path: path,
+	}
+}
+
+// Load reads the checkpoint file
+func (s *FileStore) Load() (uint64, bool, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var cp fileCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return 0, false, fmt.Errorf("failed to parse checkpoint %s: %w", s.path, err)
+	}
+
+	return cp.LastBlock, true, nil
+}
+
+// Save writes the checkpoint to a temporary file, syncs it and renames it
+// over the previous checkpoint so a crash never leaves a partial file
+func (s *FileStore) Save(block uint64) error {
+	data, err := json.Marshal(fileCheckpoint{
+		LastBlock: block,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close checkpoint: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace checkpoint: %w", err)
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
@@ -9,18 +10,43 @@ import (
 
 // Config holds all configuration for the MEV inspector
 type Config struct {
-	RPC       RPCConfig
-	Inspector InspectorConfig
-	Logging   LoggingConfig
+	RPC        RPCConfig
+	Inspector  InspectorConfig
+	Checkpoint CheckpointConfig
+	Tokens     TokensConfig
+	Pricing    PricingConfig
+	Storage    StorageConfig
+	Output     OutputConfig
+	API        APIConfig
+	Metrics    MetricsConfig
+	Logging    LoggingConfig
+
+	// Warnings lists deprecated settings found while loading, to be logged
+	// once logging is configured
+	Warnings []string
 }
 
 // RPCConfig holds Ethereum RPC configuration
 type RPCConfig struct {
-	URL            string
-	WSUrl          string
-	RetryAttempts  int
-	RetryDelay     time.Duration
-	RequestTimeout time.Duration
+	URL                 string
+	WSUrl               string
+	Endpoints           []EndpointConfig // Providers to balance between (empty = URL only)
+	RetryAttempts       int
+	RetryDelay          time.Duration
+	RequestTimeout      time.Duration
+	HealthCheckInterval time.Duration
+	MaxBlockLag         uint64        // Blocks an endpoint may trail the best one before it's avoided
+	HedgeDelay          time.Duration // Send latency-sensitive calls to a second endpoint after this long (0 = disabled)
+	MaxBatchSize        int           // Calls per JSON-RPC batch request
+	MulticallAddress    string        // Multicall3 contract ("" = batch individual eth_calls instead)
+}
+
+// EndpointConfig holds settings for one RPC provider
+type EndpointConfig struct {
+	Name      string  `mapstructure:"name"` // Label for logs and metrics (default: URL host)
+	URL       string  `mapstructure:"url"`
+	Weight    int     `mapstructure:"weight"`     // Relative share of requests (default 1)
+	RateLimit float64 `mapstructure:"rate_limit"` // Requests per second (0 = unlimited)
 }
 
 // InspectorConfig holds inspector-specific settings
@@ -29,9 +55,65 @@ type InspectorConfig struct {
 	BatchSize          int
 	StartBlock         uint64
 	WorkerCount        int
-	EnableUniswapV2    bool
-	EnableUniswapV3    bool
-	OnlyProfitable     bool // Only show arbitrages with positive net profit
+	Protocols          []string // DEX decoders to enable (empty = all registered)
+	OnlyProfitable     bool     // Only show arbitrages with positive net profit
+	DetectSandwiches   bool
+	DetectLiquidations bool
+	ReorgDepth         uint64 // Recent blocks tracked for reorg detection (0 = disabled)
+}
+
+// CheckpointConfig holds settings for persisting the last processed block
+type CheckpointConfig struct {
+	Enabled bool
+	Path    string
+	Resume  bool // Resume from the checkpoint instead of inspector.start_block
+}
+
+// TokensConfig holds settings for resolving token metadata
+type TokensConfig struct {
+	CachePath string // File caching resolved symbols and decimals ("" = memory only)
+}
+
+// PricingConfig holds settings for valuing profits in ETH and USD
+type PricingConfig struct {
+	WETH        string             // Token valued 1:1 as ETH
+	Stablecoins []StablecoinConfig // Tokens taken to be worth one US dollar, in order of preference
+}
+
+// StablecoinConfig holds one USD anchor
+type StablecoinConfig struct {
+	Address  string `mapstructure:"address"`
+	Decimals uint8  `mapstructure:"decimals"`
+}
+
+// StorageConfig holds settings for persisting results to a SQL database
+type StorageConfig struct {
+	Enabled bool
+	Driver  string // "sqlite" or "postgres"
+	DSN     string // File path for SQLite, connection string for PostgreSQL
+}
+
+// OutputConfig holds settings for the result sinks. The database sink is
+// configured by StorageConfig.
+type OutputConfig struct {
+	QueueSize      int    // Pending events per sink before new ones are dropped
+	Log            bool   // Log results through zerolog
+	FilePath       string // Append results as JSON lines ("" = disabled)
+	WebhookURL     string // POST results as JSON ("" = disabled)
+	WebhookTimeout time.Duration
+}
+
+// APIConfig holds settings for the HTTP query API
+type APIConfig struct {
+	Enabled      bool
+	ListenAddr   string
+	BufferBlocks int // Recent blocks kept in memory for queries
+}
+
+// MetricsConfig holds settings for the Prometheus metrics endpoint
+type MetricsConfig struct {
+	Enabled    bool
+	ListenAddr string
 }
 
 // LoggingConfig holds logging configuration
@@ -50,14 +132,51 @@ func Load() (*Config, error) {
 	v.SetDefault("rpc.retry_attempts", 3)
 	v.SetDefault("rpc.retry_delay", "1s")
 	v.SetDefault("rpc.request_timeout", "30s")
+	v.SetDefault("rpc.health_check_interval", "15s")
+	v.SetDefault("rpc.max_block_lag", 3)
+	v.SetDefault("rpc.hedge_delay", "0s")
+	v.SetDefault("rpc.max_batch_size", 100)
+	v.SetDefault("rpc.multicall_address", "0xcA11bde05977b3631167028862bE2a173976CA11") // Multicall3 on mainnet and most EVM chains
 
 	v.SetDefault("inspector.poll_interval", "12s")
 	v.SetDefault("inspector.batch_size", 100)
 	v.SetDefault("inspector.start_block", 0)
 	v.SetDefault("inspector.worker_count", 4)
-	v.SetDefault("inspector.enable_uniswap_v2", true)
-	v.SetDefault("inspector.enable_uniswap_v3", true)
+	v.SetDefault("inspector.protocols", []string{})
 	v.SetDefault("inspector.only_profitable", false)
+	v.SetDefault("inspector.detect_sandwiches", true)
+	v.SetDefault("inspector.detect_liquidations", true)
+	v.SetDefault("inspector.reorg_depth", 64)
+
+	v.SetDefault("checkpoint.enabled", true)
+	v.SetDefault("checkpoint.path", "checkpoint.json")
+	v.SetDefault("checkpoint.resume", true)
+
+	v.SetDefault("tokens.cache_path", "tokens.json")
+
+	v.SetDefault("pricing.weth", "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	v.SetDefault("pricing.stablecoins", []map[string]interface{}{
+		{"address": "0xA0b86991c6218b36c1D19D4a2e9Eb0cE3606eB48", "decimals": 6},  // USDC
+		{"address": "0xdAC17F958D2ee523a2206206994597C13D831ec7", "decimals": 6},  // USDT
+		{"address": "0x6B175474E89094C44Da98b954EedeAC495271d0F", "decimals": 18}, // DAI
+	})
+
+	v.SetDefault("storage.enabled", false)
+	v.SetDefault("storage.driver", "sqlite")
+	v.SetDefault("storage.dsn", "mev-inspector.db")
+
+	v.SetDefault("output.queue_size", 1024)
+	v.SetDefault("output.log", true)
+	v.SetDefault("output.file_path", "")
+	v.SetDefault("output.webhook_url", "")
+	v.SetDefault("output.webhook_timeout", "5s")
+
+	v.SetDefault("api.enabled", false)
+	v.SetDefault("api.listen_addr", ":8080")
+	v.SetDefault("api.buffer_blocks", 1000)
+
+	v.SetDefault("metrics.enabled", false)
+	v.SetDefault("metrics.listen_addr", ":9090")
 
 	v.SetDefault("logging.level", "info")
 	v.SetDefault("logging.format", "console")
@@ -78,24 +197,66 @@ func Load() (*Config, error) {
 
 	retryDelay, _ := time.ParseDuration(v.GetString("rpc.retry_delay"))
 	requestTimeout, _ := time.ParseDuration(v.GetString("rpc.request_timeout"))
+	healthCheckInterval, _ := time.ParseDuration(v.GetString("rpc.health_check_interval"))
+	hedgeDelay, _ := time.ParseDuration(v.GetString("rpc.hedge_delay"))
 	pollInterval, _ := time.ParseDuration(v.GetString("inspector.poll_interval"))
+	webhookTimeout, _ := time.ParseDuration(v.GetString("output.webhook_timeout"))
 
 	cfg := &Config{
 		RPC: RPCConfig{
-			URL:            v.GetString("rpc.url"),
-			WSUrl:          v.GetString("rpc.ws_url"),
-			RetryAttempts:  v.GetInt("rpc.retry_attempts"),
-			RetryDelay:     retryDelay,
-			RequestTimeout: requestTimeout,
+			URL:                 v.GetString("rpc.url"),
+			WSUrl:               v.GetString("rpc.ws_url"),
+			RetryAttempts:       v.GetInt("rpc.retry_attempts"),
+			RetryDelay:          retryDelay,
+			RequestTimeout:      requestTimeout,
+			HealthCheckInterval: healthCheckInterval,
+			MaxBlockLag:         v.GetUint64("rpc.max_block_lag"),
+			HedgeDelay:          hedgeDelay,
+			MaxBatchSize:        v.GetInt("rpc.max_batch_size"),
+			MulticallAddress:    v.GetString("rpc.multicall_address"),
 		},
 		Inspector: InspectorConfig{
 			PollInterval:       pollInterval,
 			BatchSize:          v.GetInt("inspector.batch_size"),
 			StartBlock:         v.GetUint64("inspector.start_block"),
 			WorkerCount:        v.GetInt("inspector.worker_count"),
-			EnableUniswapV2:    v.GetBool("inspector.enable_uniswap_v2"),
-			EnableUniswapV3:    v.GetBool("inspector.enable_uniswap_v3"),
+			Protocols:          v.GetStringSlice("inspector.protocols"),
 			OnlyProfitable:     v.GetBool("inspector.only_profitable"),
+			DetectSandwiches:   v.GetBool("inspector.detect_sandwiches"),
+			DetectLiquidations: v.GetBool("inspector.detect_liquidations"),
+			ReorgDepth:         v.GetUint64("inspector.reorg_depth"),
+		},
+		Checkpoint: CheckpointConfig{
+			Enabled: v.GetBool("checkpoint.enabled"),
+			Path:    v.GetString("checkpoint.path"),
+			Resume:  v.GetBool("checkpoint.resume"),
+		},
+		Tokens: TokensConfig{
+			CachePath: v.GetString("tokens.cache_path"),
+		},
+		Pricing: PricingConfig{
+			WETH: v.GetString("pricing.weth"),
+		},
+		Storage: StorageConfig{
+			Enabled: v.GetBool("storage.enabled"),
+			Driver:  v.GetString("storage.driver"),
+			DSN:     v.GetString("storage.dsn"),
+		},
+		Output: OutputConfig{
+			QueueSize:      v.GetInt("output.queue_size"),
+			Log:            v.GetBool("output.log"),
+			FilePath:       v.GetString("output.file_path"),
+			WebhookURL:     v.GetString("output.webhook_url"),
+			WebhookTimeout: webhookTimeout,
+		},
+		API: APIConfig{
+			Enabled:      v.GetBool("api.enabled"),
+			ListenAddr:   v.GetString("api.listen_addr"),
+			BufferBlocks: v.GetInt("api.buffer_blocks"),
+		},
+		Metrics: MetricsConfig{
+			Enabled:    v.GetBool("metrics.enabled"),
+			ListenAddr: v.GetString("metrics.listen_addr"),
 		},
 		Logging: LoggingConfig{
 			Level:  v.GetString("logging.level"),
@@ -103,5 +264,55 @@ func Load() (*Config, error) {
 		},
 	}
 
+	if err := v.UnmarshalKey("rpc.endpoints", &cfg.RPC.Endpoints); err != nil {
+		return nil, fmt.Errorf("failed to parse rpc.endpoints: %w", err)
+	}
+	if err := v.UnmarshalKey("pricing.stablecoins", &cfg.Pricing.Stablecoins); err != nil {
+		return nil, fmt.Errorf("failed to parse pricing.stablecoins: %w", err)
+	}
+
+	if err := applyLegacyProtocols(v, cfg); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
+
+// legacyProtocols maps the per-DEX switches that predate inspector.protocols
+// to the decoder they enabled
+var legacyProtocols = []struct{ key, protocol string }{
+	{"inspector.enable_uniswap_v2", "uniswap_v2"},
+	{"inspector.enable_uniswap_v3", "uniswap_v3"},
+}
+
+// applyLegacyProtocols honours enable_uniswap_v2 and enable_uniswap_v3 from
+// older configs. They only ever covered the two Uniswap decoders, both on by
+// default, so when either is set and inspector.protocols isn't, the enabled
+// ones become the protocol list, as before. An explicit protocol list takes
+// precedence.
+func applyLegacyProtocols(v *viper.Viper, cfg *Config) error {
+	var set, enabled []string
+	for _, legacy := range legacyProtocols {
+		if v.IsSet(legacy.key) {
+			set = append(set, legacy.key)
+		}
+		if !v.IsSet(legacy.key) || v.GetBool(legacy.key) {
+			enabled = append(enabled, legacy.protocol)
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+
+	if len(cfg.Inspector.Protocols) > 0 {
+		cfg.Warnings = append(cfg.Warnings, fmt.Sprintf("deprecated %s ignored because inspector.protocols is set", strings.Join(set, ", ")))
+		return nil
+	}
+	if len(enabled) == 0 {
+		return fmt.Errorf("%s disable every DEX decoder; set inspector.protocols instead", strings.Join(set, ", "))
+	}
+
+	cfg.Inspector.Protocols = enabled
+	cfg.Warnings = append(cfg.Warnings, fmt.Sprintf("deprecated %s mapped to inspector.protocols: %s", strings.Join(set, ", "), strings.Join(enabled, ", ")))
+	return nil
+}
@@ -2,66 +2,68 @@ package decoder
 
 import (
 	"context"
+	"fmt"
 	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/rs/zerolog/log"
 
-	"github.com/devlongs/mev-inspector/internal/dex/uniswapv2"
-	"github.com/devlongs/mev-inspector/internal/dex/uniswapv3"
+	"github.com/devlongs/mev-inspector/internal/dex"
+	_ "github.com/devlongs/mev-inspector/internal/dex/all" // register built-in DEX decoders
 	"github.com/devlongs/mev-inspector/internal/eth"
 	"github.com/devlongs/mev-inspector/pkg/types"
 )
 
 // Decoder combines multiple DEX decoders
 type Decoder struct {
-	v2Decoder *uniswapv2.Decoder
-	v3Decoder *uniswapv3.Decoder
-	enableV2  bool
-	enableV3  bool
+	decoders []dex.ProtocolDecoder
+	byTopic  map[common.Hash]dex.ProtocolDecoder
 }
 
-// NewDecoder creates a unified decoder for all supported DEXes
-func NewDecoder(client *eth.Client, enableV2, enableV3 bool) *Decoder {
-	var v2 *uniswapv2.Decoder
-	var v3 *uniswapv3.Decoder
-
-	if enableV2 {
-		v2 = uniswapv2.NewDecoder(client)
+// NewDecoder creates a unified decoder for the given protocols. An empty
+// protocol list enables every registered DEX decoder.
+func NewDecoder(client eth.Reader, protocols []string) (*Decoder, error) {
+	if len(protocols) == 0 {
+		protocols = dex.Protocols()
 	}
-	if enableV3 {
-		v3 = uniswapv3.NewDecoder(client)
+
+	d := &Decoder{
+		byTopic: make(map[common.Hash]dex.ProtocolDecoder),
 	}
 
-	return &Decoder{
-		v2Decoder: v2,
-		v3Decoder: v3,
-		enableV2:  enableV2,
-		enableV3:  enableV3,
+	for _, name := range protocols {
+		pd, err := dex.New(name, client)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, topic := range pd.EventSignatures() {
+			if existing, ok := d.byTopic[topic]; ok {
+				return nil, fmt.Errorf("event %s claimed by both %s and %s", topic.Hex(), existing.Name(), pd.Name())
+			}
+			d.byTopic[topic] = pd
+		}
+		d.decoders = append(d.decoders, pd)
 	}
+
+	log.Info().Strs("protocols", protocols).Msg("DEX decoders enabled")
+
+	return d, nil
 }
 
 // GetAllSwapLogs fetches swap logs from all enabled DEXes
 func (d *Decoder) GetAllSwapLogs(ctx context.Context, fromBlock, toBlock uint64) ([]ethtypes.Log, error) {
 	var allLogs []ethtypes.Log
 
-	if d.enableV2 && d.v2Decoder != nil {
-		v2Logs, err := d.v2Decoder.GetSwapLogs(ctx, fromBlock, toBlock)
+	for _, pd := range d.decoders {
+		logs, err := pd.GetSwapLogs(ctx, fromBlock, toBlock)
 		if err != nil {
-			log.Warn().Err(err).Msg("Failed to get V2 swap logs")
-		} else {
-			allLogs = append(allLogs, v2Logs...)
-		}
-	}
-
-	if d.enableV3 && d.v3Decoder != nil {
-		v3Logs, err := d.v3Decoder.GetSwapLogs(ctx, fromBlock, toBlock)
-		if err != nil {
-			log.Warn().Err(err).Msg("Failed to get V3 swap logs")
-		} else {
-			allLogs = append(allLogs, v3Logs...)
+			// Skipping the protocol would silently drop its swaps from the
+			// whole range
+			return nil, fmt.Errorf("failed to get %s swap logs: %w", pd.Name(), err)
 		}
+		allLogs = append(allLogs, logs...)
 	}
 
 	// Sort by block number and log index
@@ -75,25 +77,49 @@ func (d *Decoder) GetAllSwapLogs(ctx context.Context, fromBlock, toBlock uint64)
 	return allLogs, nil
 }
 
+// PrefetchPools resolves the metadata of every pool in logs ahead of
+// decoding, for the protocols that support it. Failures are only logged;
+// those pools are fetched individually when their swaps are decoded.
+func (d *Decoder) PrefetchPools(ctx context.Context, logs []ethtypes.Log) {
+	pools := make(map[dex.ProtocolDecoder][]common.Address)
+	seen := make(map[common.Address]bool)
+
+	for _, l := range logs {
+		if len(l.Topics) == 0 || seen[l.Address] {
+			continue
+		}
+		pd, ok := d.byTopic[l.Topics[0]]
+		if !ok {
+			continue
+		}
+		seen[l.Address] = true
+		pools[pd] = append(pools[pd], l.Address)
+	}
+
+	for pd, addresses := range pools {
+		prefetcher, ok := pd.(dex.PoolPrefetcher)
+		if !ok {
+			continue
+		}
+		if err := prefetcher.PrefetchPools(ctx, addresses); err != nil {
+			log.Debug().Err(err).Str("protocol", pd.Name()).Msg("Failed to prefetch pool info")
+		}
+	}
+}
+
 // DecodeSwapLog decodes a swap log based on its event signature
 func (d *Decoder) DecodeSwapLog(ctx context.Context, log ethtypes.Log) (*types.Swap, error) {
-	// Check if it's a V2 or V3 swap based on topic
 	if len(log.Topics) == 0 {
 		return nil, nil
 	}
 
-	switch log.Topics[0] {
-	case uniswapv2.SwapEventSignature:
-		if d.v2Decoder != nil {
-			return d.v2Decoder.DecodeSwapLog(ctx, log)
-		}
-	case uniswapv3.SwapEventSignature:
-		if d.v3Decoder != nil {
-			return d.v3Decoder.DecodeSwapLog(ctx, log)
-		}
+	// Dispatch to the protocol decoder registered for this event
+	pd, ok := d.byTopic[log.Topics[0]]
+	if !ok {
+		return nil, nil
 	}
 
-	return nil, nil
+	return pd.DecodeSwapLog(ctx, log)
 }
 
 // GroupSwapsByTransaction groups swap logs by their transaction hash
@@ -111,10 +137,14 @@ func (d *Decoder) GroupSwapsByTransaction(logs []ethtypes.Log) map[common.Hash][
 func (d *Decoder) DecodeSwapsForTransaction(ctx context.Context, logs []ethtypes.Log) ([]types.Swap, error) {
 	var swaps []types.Swap
 
-	for _, log := range logs {
-		swap, err := d.DecodeSwapLog(ctx, log)
+	for _, l := range logs {
+		swap, err := d.DecodeSwapLog(ctx, l)
 		if err != nil {
 			// Log error but continue processing other swaps
+			log.Warn().Err(err).
+				Str("txHash", l.TxHash.Hex()).
+				Uint("logIndex", l.Index).
+				Msg("Failed to decode swap log")
 			continue
 		}
 		if swap != nil {
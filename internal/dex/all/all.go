@@ -0,0 +1,11 @@
+// Package all registers every built-in DEX decoder with the dex registry.
+// Adding a protocol only requires a blank import here.
+package all
+
+import (
+	_ "github.com/devlongs/mev-inspector/internal/dex/balancer"
+	_ "github.com/devlongs/mev-inspector/internal/dex/curve"
+	_ "github.com/devlongs/mev-inspector/internal/dex/uniswapv2"
+	_ "github.com/devlongs/mev-inspector/internal/dex/uniswapv3"
+	_ "github.com/devlongs/mev-inspector/internal/dex/uniswapv4"
+)
@@ -0,0 +1,118 @@
+package balancer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/devlongs/mev-inspector/internal/dex"
+	"github.com/devlongs/mev-inspector/internal/eth"
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+// Balancer V2 Vault Swap event signature
+// event Swap(bytes32 indexed poolId, address indexed tokenIn, address indexed tokenOut, uint256 amountIn, uint256 amountOut)
+var SwapEventSignature = common.HexToHash("0x2170c741c41531aec20e7c107c24eecfdd15e69c9bb0a8dd37b1840b9e0b207b")
+
+// Balancer V2 Vault address on mainnet; every pool swaps through it
+var Vault = common.HexToAddress("0xBA12222222228d8Ba445958a75a0704d566BF2C8")
+
+// ProtocolName identifies Balancer V2 in config and decoded swaps
+const ProtocolName = "balancer_v2"
+
+func init() {
+	dex.Register(ProtocolName, func(client eth.Reader) dex.ProtocolDecoder {
+		return NewDecoder(client)
+	})
+}
+
+// Decoder decodes Balancer V2 Vault swap events
+type Decoder struct {
+	client eth.Reader
+}
+
+// NewDecoder creates a new Balancer V2 decoder
+func NewDecoder(client eth.Reader) *Decoder {
+	return &Decoder{
+		client: client,
+	}
+}
+
+// Name returns the protocol identifier
+func (d *Decoder) Name() string {
+	return ProtocolName
+}
+
+// EventSignatures returns the log topics handled by this decoder
+func (d *Decoder) EventSignatures() []common.Hash {
+	return []common.Hash{SwapEventSignature}
+}
+
+// GetSwapLogs fetches all Balancer V2 Vault swap logs in a block range
+func (d *Decoder) GetSwapLogs(ctx context.Context, fromBlock, toBlock uint64) ([]ethtypes.Log, error) {
+	query := ethereum.FilterQuery{
+		FromBlock: big.NewInt(int64(fromBlock)),
+		ToBlock:   big.NewInt(int64(toBlock)),
+		Addresses: []common.Address{Vault},
+		Topics: [][]common.Hash{
+			{SwapEventSignature},
+		},
+	}
+
+	return d.client.GetLogs(ctx, query)
+}
+
+// DecodeSwapLog decodes a single Vault swap log into a Swap struct.
+// The event names both tokens, so no pool lookup is needed: tokenIn is
+// reported as Token0 and tokenOut as Token1, which works for pools of any size.
+func (d *Decoder) DecodeSwapLog(ctx context.Context, log ethtypes.Log) (*types.Swap, error) {
+	if len(log.Topics) < 4 {
+		return nil, fmt.Errorf("invalid swap log: expected 4 topics, got %d", len(log.Topics))
+	}
+
+	if log.Topics[0] != SwapEventSignature {
+		return nil, fmt.Errorf("not a Balancer V2 swap event")
+	}
+
+	if log.Address != Vault {
+		return nil, fmt.Errorf("swap event not emitted by the Balancer V2 Vault")
+	}
+
+	// Decode indexed parameters from topics
+	poolID := log.Topics[1]
+	tokenIn := common.HexToAddress(log.Topics[2].Hex())
+	tokenOut := common.HexToAddress(log.Topics[3].Hex())
+
+	// Decode non-indexed parameters from data
+	if len(log.Data) < 64 {
+		return nil, fmt.Errorf("invalid swap log data length: expected 64 bytes, got %d", len(log.Data))
+	}
+
+	amountIn := new(big.Int).SetBytes(log.Data[0:32])
+	amountOut := new(big.Int).SetBytes(log.Data[32:64])
+
+	return &types.Swap{
+		TxHash:      log.TxHash,
+		BlockNumber: log.BlockNumber,
+		LogIndex:    log.Index,
+		Pool:        PoolAddress(poolID),
+		PoolID:      poolID,
+		Protocol:    ProtocolName,
+		Token0:      tokenIn,
+		Token1:      tokenOut,
+		Amount0In:   amountIn,
+		Amount1In:   big.NewInt(0),
+		Amount0Out:  big.NewInt(0),
+		Amount1Out:  amountOut,
+	}, nil
+}
+
+// PoolAddress extracts the pool contract address from a Balancer pool ID,
+// which stores it in the first 20 bytes
+func PoolAddress(poolID common.Hash) common.Address {
+	return common.BytesToAddress(poolID[:20])
+}
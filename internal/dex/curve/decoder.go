@@ -0,0 +1,328 @@
+package curve
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+
+	"github.com/devlongs/mev-inspector/internal/dex"
+	"github.com/devlongs/mev-inspector/internal/eth"
+	"github.com/devlongs/mev-inspector/internal/metrics"
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+// Curve StableSwap TokenExchange event signature
+// event TokenExchange(address indexed buyer, int128 sold_id, uint256 tokens_sold, int128 bought_id, uint256 tokens_bought)
+var TokenExchangeSignature = common.HexToHash("0x8b3e96f2b889fa771c53c981b40daf005f63f637f1869f707052d15a3dd97140")
+
+// Curve CryptoSwap TokenExchange event signature (uint256 coin indices)
+// event TokenExchange(address indexed buyer, uint256 sold_id, uint256 tokens_sold, uint256 bought_id, uint256 tokens_bought)
+var CryptoTokenExchangeSignature = common.HexToHash("0xb2e76ae99761dc136e598d4a629bb347eccb9532a5f8bbd72e18467c3c34cc98")
+
+// Curve TokenExchangeUnderlying event signature (lending and meta pools)
+// event TokenExchangeUnderlying(address indexed buyer, int128 sold_id, uint256 tokens_sold, int128 bought_id, uint256 tokens_bought)
+var TokenExchangeUnderlyingSignature = common.HexToHash("0xd013ca23e77a65003c2c659c5442c00c805371b7fc1ebd4c206c41d1536bd90b")
+
+// Curve pools holding native ETH report this placeholder from coins(i)
+var NativeETH = common.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE")
+
+// WETH address on mainnet, substituted for NativeETH so Curve legs chain with other DEXes
+var WETH = common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+
+// ProtocolName identifies Curve in config and decoded swaps
+const ProtocolName = "curve"
+
+// maxCoins bounds coin indices; Curve pools hold at most 8 coins
+const maxCoins = 8
+
+func init() {
+	dex.Register(ProtocolName, func(client eth.Reader) dex.ProtocolDecoder {
+		return NewDecoder(client)
+	})
+}
+
+// Decoder decodes Curve StableSwap and CryptoSwap exchange events
+type Decoder struct {
+	client    eth.Reader
+	poolCache map[common.Address]*PoolInfo
+	mu        sync.RWMutex
+}
+
+// PoolInfo holds cached coin addresses of a Curve pool, keyed by coin index
+type PoolInfo struct {
+	Coins           map[int64]common.Address
+	UnderlyingCoins map[int64]common.Address
+}
+
+// NewDecoder creates a new Curve decoder
+func NewDecoder(client eth.Reader) *Decoder {
+	return &Decoder{
+		client:    client,
+		poolCache: make(map[common.Address]*PoolInfo),
+	}
+}
+
+// Name returns the protocol identifier
+func (d *Decoder) Name() string {
+	return ProtocolName
+}
+
+// EventSignatures returns the log topics handled by this decoder
+func (d *Decoder) EventSignatures() []common.Hash {
+	return []common.Hash{
+		TokenExchangeSignature,
+		CryptoTokenExchangeSignature,
+		TokenExchangeUnderlyingSignature,
+	}
+}
+
+// GetSwapLogs fetches all Curve exchange logs in a block range
+func (d *Decoder) GetSwapLogs(ctx context.Context, fromBlock, toBlock uint64) ([]ethtypes.Log, error) {
+	query := ethereum.FilterQuery{
+		FromBlock: big.NewInt(int64(fromBlock)),
+		ToBlock:   big.NewInt(int64(toBlock)),
+		Topics: [][]common.Hash{
+			d.EventSignatures(),
+		},
+	}
+
+	return d.client.GetLogs(ctx, query)
+}
+
+// DecodeSwapLog decodes a single Curve exchange log into a Swap struct.
+// The sold coin is reported as Token0 and the bought coin as Token1.
+func (d *Decoder) DecodeSwapLog(ctx context.Context, log ethtypes.Log) (*types.Swap, error) {
+	if len(log.Topics) < 2 {
+		return nil, fmt.Errorf("invalid exchange log: expected 2 topics, got %d", len(log.Topics))
+	}
+
+	var underlying bool
+	switch log.Topics[0] {
+	case TokenExchangeSignature, CryptoTokenExchangeSignature:
+		underlying = false
+	case TokenExchangeUnderlyingSignature:
+		underlying = true
+	default:
+		return nil, fmt.Errorf("not a Curve exchange event")
+	}
+
+	// Decode indexed parameters from topics
+	buyer := common.HexToAddress(log.Topics[1].Hex())
+
+	// Decode non-indexed parameters from data
+	// sold_id, tokens_sold, bought_id, tokens_bought
+	if len(log.Data) < 128 {
+		return nil, fmt.Errorf("invalid exchange log data length: expected 128 bytes, got %d", len(log.Data))
+	}
+
+	soldID, err := decodeCoinIndex(log.Data[0:32])
+	if err != nil {
+		return nil, err
+	}
+	tokensSold := new(big.Int).SetBytes(log.Data[32:64])
+
+	boughtID, err := decodeCoinIndex(log.Data[64:96])
+	if err != nil {
+		return nil, err
+	}
+	tokensBought := new(big.Int).SetBytes(log.Data[96:128])
+
+	tokenSold, err := d.getCoin(ctx, log.Address, soldID, underlying)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sold coin: %w", err)
+	}
+
+	tokenBought, err := d.getCoin(ctx, log.Address, boughtID, underlying)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bought coin: %w", err)
+	}
+
+	return &types.Swap{
+		TxHash:      log.TxHash,
+		BlockNumber: log.BlockNumber,
+		LogIndex:    log.Index,
+		Pool:        log.Address,
+		Protocol:    ProtocolName,
+		Sender:      buyer,
+		Recipient:   buyer,
+		Token0:      tokenSold,
+		Token1:      tokenBought,
+		Amount0In:   tokensSold,
+		Amount1In:   big.NewInt(0),
+		Amount0Out:  big.NewInt(0),
+		Amount1Out:  tokensBought,
+	}, nil
+}
+
+// decodeCoinIndex decodes an int128/uint256 coin index from an ABI word
+func decodeCoinIndex(word []byte) (int64, error) {
+	index := new(big.Int).SetBytes(word)
+	if !index.IsInt64() || index.Int64() >= maxCoins {
+		return 0, fmt.Errorf("invalid coin index %s", index.String())
+	}
+	return index.Int64(), nil
+}
+
+// getCoin resolves and caches the token address for a coin index
+func (d *Decoder) getCoin(ctx context.Context, poolAddress common.Address, index int64, underlying bool) (common.Address, error) {
+	// Check cache first
+	d.mu.RLock()
+	coin, ok := d.cachedCoin(poolAddress, index, underlying)
+	d.mu.RUnlock()
+	metrics.PoolCacheLookup("curve", ok)
+	if ok {
+		return coin, nil
+	}
+
+	var err error
+	if underlying {
+		coin, err = d.fetchUnderlyingCoin(ctx, poolAddress, index)
+	} else {
+		coin, err = d.callCoins(ctx, poolAddress, index)
+	}
+	if err != nil {
+		return common.Address{}, err
+	}
+	if coin == NativeETH {
+		coin = WETH
+	}
+
+	// Cache the result
+	d.mu.Lock()
+	info, ok := d.poolCache[poolAddress]
+	if !ok {
+		info = &PoolInfo{
+			Coins:           make(map[int64]common.Address),
+			UnderlyingCoins: make(map[int64]common.Address),
+		}
+		d.poolCache[poolAddress] = info
+	}
+	if underlying {
+		info.UnderlyingCoins[index] = coin
+	} else {
+		info.Coins[index] = coin
+	}
+	d.mu.Unlock()
+
+	log.Debug().
+		Str("pool", poolAddress.Hex()).
+		Int64("index", index).
+		Bool("underlying", underlying).
+		Str("coin", coin.Hex()).
+		Msg("Cached Curve coin")
+
+	return coin, nil
+}
+
+// cachedCoin looks up a coin in the pool cache; the caller must hold d.mu
+func (d *Decoder) cachedCoin(poolAddress common.Address, index int64, underlying bool) (common.Address, bool) {
+	info, ok := d.poolCache[poolAddress]
+	if !ok {
+		return common.Address{}, false
+	}
+
+	var coin common.Address
+	if underlying {
+		coin, ok = info.UnderlyingCoins[index]
+	} else {
+		coin, ok = info.Coins[index]
+	}
+
+	return coin, ok
+}
+
+// fetchUnderlyingCoin resolves an underlying coin for lending pools
+// (underlying_coins) and metapools (coin 0, then the base pool's coins)
+func (d *Decoder) fetchUnderlyingCoin(ctx context.Context, poolAddress common.Address, index int64) (common.Address, error) {
+	// underlying_coins(uint256) selector: 0xb9947eb0
+	// underlying_coins(int128) selector: 0xb739953e
+	if coin, err := d.callIndexed(ctx, poolAddress, "b9947eb0", index); err == nil {
+		return coin, nil
+	}
+	if coin, err := d.callIndexed(ctx, poolAddress, "b739953e", index); err == nil {
+		return coin, nil
+	}
+
+	// Metapool: index 0 is the pool's own coin, the rest come from the base pool
+	if index == 0 {
+		return d.callCoins(ctx, poolAddress, 0)
+	}
+
+	// base_coins(uint256) selector: 0x87cb4f57
+	if coin, err := d.callIndexed(ctx, poolAddress, "87cb4f57", index-1); err == nil {
+		return coin, nil
+	}
+
+	basePool, err := d.callBasePool(ctx, poolAddress)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to resolve underlying coin %d: %w", index, err)
+	}
+
+	return d.callCoins(ctx, basePool, index-1)
+}
+
+// callCoins calls coins(i) on a Curve pool, falling back to the int128 variant
+func (d *Decoder) callCoins(ctx context.Context, poolAddress common.Address, index int64) (common.Address, error) {
+	// coins(uint256) selector: 0xc6610657
+	coin, err := d.callIndexed(ctx, poolAddress, "c6610657", index)
+	if err == nil {
+		return coin, nil
+	}
+
+	// coins(int128) selector: 0x23746eb8 (older pools)
+	return d.callIndexed(ctx, poolAddress, "23746eb8", index)
+}
+
+// callBasePool calls base_pool() on a Curve metapool
+func (d *Decoder) callBasePool(ctx context.Context, poolAddress common.Address) (common.Address, error) {
+	// base_pool() selector: 0x5d6362bb
+	data := common.Hex2Bytes("5d6362bb")
+
+	msg := ethereum.CallMsg{
+		To:   &poolAddress,
+		Data: data,
+	}
+
+	result, err := d.client.CallContract(ctx, msg, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	if len(result) < 32 {
+		return common.Address{}, fmt.Errorf("invalid base_pool response")
+	}
+
+	return common.BytesToAddress(result[12:32]), nil
+}
+
+// callIndexed calls an address-returning getter that takes a single index argument
+func (d *Decoder) callIndexed(ctx context.Context, poolAddress common.Address, selector string, index int64) (common.Address, error) {
+	data := append(common.Hex2Bytes(selector), common.LeftPadBytes(big.NewInt(index).Bytes(), 32)...)
+
+	msg := ethereum.CallMsg{
+		To:   &poolAddress,
+		Data: data,
+	}
+
+	result, err := d.client.CallContract(ctx, msg, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	if len(result) < 32 {
+		return common.Address{}, fmt.Errorf("invalid coin response")
+	}
+
+	coin := common.BytesToAddress(result[12:32])
+	if coin == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("no coin at index %d", index)
+	}
+
+	return coin, nil
+}
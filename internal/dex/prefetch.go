@@ -0,0 +1,54 @@
+package dex
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/devlongs/mev-inspector/internal/eth"
+)
+
+// PrefetchWords calls every selector on each uncached pool in a single
+// multicall and hands store the first 32-byte word of each result, in the
+// order of selectors. Pools with a failed or short result are skipped so
+// their swaps fetch them individually. It returns the number of pools
+// queried.
+func PrefetchWords(ctx context.Context, client eth.Reader, pools []common.Address, selectors [][]byte, cached func(common.Address) bool, store func(pool common.Address, words [][]byte)) (int, error) {
+	var missing []common.Address
+	for _, pool := range pools {
+		if !cached(pool) {
+			missing = append(missing, pool)
+		}
+	}
+
+	if len(missing) == 0 {
+		return 0, nil
+	}
+
+	calls := make([]eth.Call, 0, len(selectors)*len(missing))
+	for _, pool := range missing {
+		for _, selector := range selectors {
+			calls = append(calls, eth.Call{Target: pool, Data: selector})
+		}
+	}
+
+	results, err := client.Multicall(ctx, calls, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	for idx, pool := range missing {
+		words := make([][]byte, 0, len(selectors))
+		for _, result := range results[idx*len(selectors) : (idx+1)*len(selectors)] {
+			if result.Err != nil || len(result.Data) < 32 {
+				break
+			}
+			words = append(words, result.Data[:32])
+		}
+		if len(words) == len(selectors) {
+			store(pool, words)
+		}
+	}
+
+	return len(missing), nil
+}
@@ -0,0 +1,84 @@
+package dex
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/devlongs/mev-inspector/internal/eth"
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+// ProtocolDecoder decodes swap events for a single DEX protocol
+type ProtocolDecoder interface {
+	// Name returns the protocol identifier used in config and types.Swap.Protocol
+	Name() string
+	// EventSignatures returns the log topics this decoder handles
+	EventSignatures() []common.Hash
+	// GetSwapLogs fetches all swap logs for the protocol in a block range
+	GetSwapLogs(ctx context.Context, fromBlock, toBlock uint64) ([]ethtypes.Log, error)
+	// DecodeSwapLog decodes a single swap log into a Swap struct
+	DecodeSwapLog(ctx context.Context, log ethtypes.Log) (*types.Swap, error)
+}
+
+// PoolPrefetcher is implemented by protocol decoders that can resolve the
+// metadata of many pools in a few round trips ahead of decoding
+type PoolPrefetcher interface {
+	// PrefetchPools caches the metadata of every uncached pool. Pools that
+	// can't be resolved are left to be fetched when their swaps are decoded.
+	PrefetchPools(ctx context.Context, pools []common.Address) error
+}
+
+// Factory creates a protocol decoder bound to an Ethereum client
+type Factory func(client eth.Reader) ProtocolDecoder
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a protocol decoder available by name. It is intended to be
+// called from the init function of each DEX package and panics on duplicates.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if factory == nil {
+		panic("dex: Register factory is nil for " + name)
+	}
+	if _, dup := registry[name]; dup {
+		panic("dex: Register called twice for " + name)
+	}
+	registry[name] = factory
+}
+
+// Protocols returns the sorted names of all registered protocols
+func Protocols() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// New creates the protocol decoder registered under name
+func New(name string, client eth.Reader) (ProtocolDecoder, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown DEX protocol %q (registered: %v)", name, Protocols())
+	}
+
+	return factory(client), nil
+}
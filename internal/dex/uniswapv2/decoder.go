@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/big"
 	"strings"
+	"sync"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -12,7 +13,9 @@ import (
 	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/rs/zerolog/log"
 
+	"github.com/devlongs/mev-inspector/internal/dex"
 	"github.com/devlongs/mev-inspector/internal/eth"
+	"github.com/devlongs/mev-inspector/internal/metrics"
 	"github.com/devlongs/mev-inspector/pkg/types"
 )
 
@@ -26,14 +29,30 @@ var SyncEventSignature = common.HexToHash("0x1c411e9a96e071241c2f21f7726b17ae89e
 
 // Common Uniswap V2 factory addresses
 var (
-	UniswapV2Factory   = common.HexToAddress("0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f")
-	SushiswapFactory   = common.HexToAddress("0xC0AEe478e3658e2610c5F7A4A2E1777cE9e4f2Ac")
+	UniswapV2Factory = common.HexToAddress("0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f")
+	SushiswapFactory = common.HexToAddress("0xC0AEe478e3658e2610c5F7A4A2E1777cE9e4f2Ac")
 )
 
+// Pool getter selectors
+var (
+	token0Selector = common.Hex2Bytes("0dfe1681") // token0()
+	token1Selector = common.Hex2Bytes("d21220a7") // token1()
+)
+
+// ProtocolName identifies Uniswap V2 in config and decoded swaps
+const ProtocolName = "uniswap_v2"
+
+func init() {
+	dex.Register(ProtocolName, func(client eth.Reader) dex.ProtocolDecoder {
+		return NewDecoder(client)
+	})
+}
+
 // Decoder decodes Uniswap V2 swap events
 type Decoder struct {
-	client    *eth.Client
+	client    eth.Reader
 	poolCache map[common.Address]*PoolInfo
+	mu        sync.RWMutex
 }
 
 // PoolInfo holds cached information about a V2 pool
@@ -45,13 +64,23 @@ type PoolInfo struct {
 }
 
 // NewDecoder creates a new Uniswap V2 decoder
-func NewDecoder(client *eth.Client) *Decoder {
+func NewDecoder(client eth.Reader) *Decoder {
 	return &Decoder{
 		client:    client,
 		poolCache: make(map[common.Address]*PoolInfo),
 	}
 }
 
+// Name returns the protocol identifier
+func (d *Decoder) Name() string {
+	return ProtocolName
+}
+
+// EventSignatures returns the log topics handled by this decoder
+func (d *Decoder) EventSignatures() []common.Hash {
+	return []common.Hash{SwapEventSignature}
+}
+
 // GetSwapLogs fetches all Uniswap V2 swap logs in a block range
 func (d *Decoder) GetSwapLogs(ctx context.Context, fromBlock, toBlock uint64) ([]ethtypes.Log, error) {
 	query := ethereum.FilterQuery{
@@ -100,7 +129,7 @@ func (d *Decoder) DecodeSwapLog(ctx context.Context, log ethtypes.Log) (*types.S
 		BlockNumber: log.BlockNumber,
 		LogIndex:    log.Index,
 		Pool:        log.Address,
-		Protocol:    "uniswap_v2",
+		Protocol:    ProtocolName,
 		Sender:      sender,
 		Recipient:   recipient,
 		Token0:      poolInfo.Token0,
@@ -115,7 +144,11 @@ func (d *Decoder) DecodeSwapLog(ctx context.Context, log ethtypes.Log) (*types.S
 // getPoolInfo fetches and caches pool information
 func (d *Decoder) getPoolInfo(ctx context.Context, poolAddress common.Address) (*PoolInfo, error) {
 	// Check cache first
-	if info, ok := d.poolCache[poolAddress]; ok {
+	d.mu.RLock()
+	info, ok := d.poolCache[poolAddress]
+	d.mu.RUnlock()
+	metrics.PoolCacheLookup("uniswap_v2", ok)
+	if ok {
 		return info, nil
 	}
 
@@ -130,13 +163,15 @@ func (d *Decoder) getPoolInfo(ctx context.Context, poolAddress common.Address) (
 		return nil, fmt.Errorf("failed to get token1: %w", err)
 	}
 
-	info := &PoolInfo{
+	info = &PoolInfo{
 		Token0: token0,
 		Token1: token1,
 	}
 
 	// Cache the result
+	d.mu.Lock()
 	d.poolCache[poolAddress] = info
+	d.mu.Unlock()
 
 	log.Debug().
 		Str("pool", poolAddress.Hex()).
@@ -147,14 +182,42 @@ func (d *Decoder) getPoolInfo(ctx context.Context, poolAddress common.Address) (
 	return info, nil
 }
 
+// PrefetchPools fetches the token0 and token1 of every uncached pool in a
+// single multicall
+func (d *Decoder) PrefetchPools(ctx context.Context, pools []common.Address) error {
+	selectors := [][]byte{token0Selector, token1Selector}
+	n, err := dex.PrefetchWords(ctx, d.client, pools, selectors, d.cached, func(pool common.Address, words [][]byte) {
+		d.mu.Lock()
+		d.poolCache[pool] = &PoolInfo{
+			Token0: common.BytesToAddress(words[0][12:]),
+			Token1: common.BytesToAddress(words[1][12:]),
+		}
+		d.mu.Unlock()
+	})
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		log.Debug().Int("pools", n).Msg("Prefetched V2 pool info")
+	}
+
+	return nil
+}
+
+// cached reports whether a pool's info is cached
+func (d *Decoder) cached(pool common.Address) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	_, ok := d.poolCache[pool]
+	return ok
+}
+
 // callToken0 calls the token0() function on a V2 pair contract
 func (d *Decoder) callToken0(ctx context.Context, poolAddress common.Address) (common.Address, error) {
-	// token0() selector: 0x0dfe1681
-	data := common.Hex2Bytes("0dfe1681")
-
 	msg := ethereum.CallMsg{
 		To:   &poolAddress,
-		Data: data,
+		Data: token0Selector,
 	}
 
 	result, err := d.client.CallContract(ctx, msg, nil)
@@ -171,12 +234,9 @@ func (d *Decoder) callToken0(ctx context.Context, poolAddress common.Address) (c
 
 // callToken1 calls the token1() function on a V2 pair contract
 func (d *Decoder) callToken1(ctx context.Context, poolAddress common.Address) (common.Address, error) {
-	// token1() selector: 0xd21220a7
-	data := common.Hex2Bytes("d21220a7")
-
 	msg := ethereum.CallMsg{
 		To:   &poolAddress,
-		Data: data,
+		Data: token1Selector,
 	}
 
 	result, err := d.client.CallContract(ctx, msg, nil)
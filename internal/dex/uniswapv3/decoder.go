@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/big"
 	"strings"
+	"sync"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -12,7 +13,9 @@ import (
 	ethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/rs/zerolog/log"
 
+	"github.com/devlongs/mev-inspector/internal/dex"
 	"github.com/devlongs/mev-inspector/internal/eth"
+	"github.com/devlongs/mev-inspector/internal/metrics"
 	"github.com/devlongs/mev-inspector/pkg/types"
 )
 
@@ -23,10 +26,27 @@ var SwapEventSignature = common.HexToHash("0xc42079f94a6350d7e6235f29174924f928c
 // Common Uniswap V3 factory address
 var UniswapV3Factory = common.HexToAddress("0x1F98431c8aD98523631AE4a59f267346ea31F984")
 
+// Pool getter selectors
+var (
+	token0Selector = common.Hex2Bytes("0dfe1681") // token0()
+	token1Selector = common.Hex2Bytes("d21220a7") // token1()
+	feeSelector    = common.Hex2Bytes("ddca3f43") // fee()
+)
+
+// ProtocolName identifies Uniswap V3 in config and decoded swaps
+const ProtocolName = "uniswap_v3"
+
+func init() {
+	dex.Register(ProtocolName, func(client eth.Reader) dex.ProtocolDecoder {
+		return NewDecoder(client)
+	})
+}
+
 // Decoder decodes Uniswap V3 swap events
 type Decoder struct {
-	client    *eth.Client
+	client    eth.Reader
 	poolCache map[common.Address]*PoolInfo
+	mu        sync.RWMutex
 }
 
 // PoolInfo holds cached information about a V3 pool
@@ -37,13 +57,23 @@ type PoolInfo struct {
 }
 
 // NewDecoder creates a new Uniswap V3 decoder
-func NewDecoder(client *eth.Client) *Decoder {
+func NewDecoder(client eth.Reader) *Decoder {
 	return &Decoder{
 		client:    client,
 		poolCache: make(map[common.Address]*PoolInfo),
 	}
 }
 
+// Name returns the protocol identifier
+func (d *Decoder) Name() string {
+	return ProtocolName
+}
+
+// EventSignatures returns the log topics handled by this decoder
+func (d *Decoder) EventSignatures() []common.Hash {
+	return []common.Hash{SwapEventSignature}
+}
+
 // GetSwapLogs fetches all Uniswap V3 swap logs in a block range
 func (d *Decoder) GetSwapLogs(ctx context.Context, fromBlock, toBlock uint64) ([]ethtypes.Log, error) {
 	query := ethereum.FilterQuery{
@@ -130,7 +160,7 @@ func (d *Decoder) DecodeSwapLog(ctx context.Context, log ethtypes.Log) (*types.S
 		BlockNumber:  log.BlockNumber,
 		LogIndex:     log.Index,
 		Pool:         log.Address,
-		Protocol:     "uniswap_v3",
+		Protocol:     ProtocolName,
 		Sender:       sender,
 		Recipient:    recipient,
 		Token0:       poolInfo.Token0,
@@ -148,7 +178,11 @@ func (d *Decoder) DecodeSwapLog(ctx context.Context, log ethtypes.Log) (*types.S
 // getPoolInfo fetches and caches pool information
 func (d *Decoder) getPoolInfo(ctx context.Context, poolAddress common.Address) (*PoolInfo, error) {
 	// Check cache first
-	if info, ok := d.poolCache[poolAddress]; ok {
+	d.mu.RLock()
+	info, ok := d.poolCache[poolAddress]
+	d.mu.RUnlock()
+	metrics.PoolCacheLookup("uniswap_v3", ok)
+	if ok {
 		return info, nil
 	}
 
@@ -168,14 +202,16 @@ func (d *Decoder) getPoolInfo(ctx context.Context, poolAddress common.Address) (
 		return nil, fmt.Errorf("failed to get fee: %w", err)
 	}
 
-	info := &PoolInfo{
+	info = &PoolInfo{
 		Token0: token0,
 		Token1: token1,
 		Fee:    fee,
 	}
 
 	// Cache the result
+	d.mu.Lock()
 	d.poolCache[poolAddress] = info
+	d.mu.Unlock()
 
 	log.Debug().
 		Str("pool", poolAddress.Hex()).
@@ -187,14 +223,43 @@ func (d *Decoder) getPoolInfo(ctx context.Context, poolAddress common.Address) (
 	return info, nil
 }
 
+// PrefetchPools fetches the token0, token1 and fee of every uncached pool in a
+// single multicall
+func (d *Decoder) PrefetchPools(ctx context.Context, pools []common.Address) error {
+	selectors := [][]byte{token0Selector, token1Selector, feeSelector}
+	n, err := dex.PrefetchWords(ctx, d.client, pools, selectors, d.cached, func(pool common.Address, words [][]byte) {
+		d.mu.Lock()
+		d.poolCache[pool] = &PoolInfo{
+			Token0: common.BytesToAddress(words[0][12:]),
+			Token1: common.BytesToAddress(words[1][12:]),
+			Fee:    uint32(new(big.Int).SetBytes(words[2]).Uint64()),
+		}
+		d.mu.Unlock()
+	})
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		log.Debug().Int("pools", n).Msg("Prefetched V3 pool info")
+	}
+
+	return nil
+}
+
+// cached reports whether a pool's info is cached
+func (d *Decoder) cached(pool common.Address) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	_, ok := d.poolCache[pool]
+	return ok
+}
+
 // callToken0 calls the token0() function on a V3 pool contract
 func (d *Decoder) callToken0(ctx context.Context, poolAddress common.Address) (common.Address, error) {
-	// token0() selector: 0x0dfe1681
-	data := common.Hex2Bytes("0dfe1681")
-
 	msg := ethereum.CallMsg{
 		To:   &poolAddress,
-		Data: data,
+		Data: token0Selector,
 	}
 
 	result, err := d.client.CallContract(ctx, msg, nil)
@@ -211,12 +276,9 @@ func (d *Decoder) callToken0(ctx context.Context, poolAddress common.Address) (c
 
 // callToken1 calls the token1() function on a V3 pool contract
 func (d *Decoder) callToken1(ctx context.Context, poolAddress common.Address) (common.Address, error) {
-	// token1() selector: 0xd21220a7
-	data := common.Hex2Bytes("d21220a7")
-
 	msg := ethereum.CallMsg{
 		To:   &poolAddress,
-		Data: data,
+		Data: token1Selector,
 	}
 
 	result, err := d.client.CallContract(ctx, msg, nil)
@@ -233,12 +295,9 @@ func (d *Decoder) callToken1(ctx context.Context, poolAddress common.Address) (c
 
 // callFee calls the fee() function on a V3 pool contract
 func (d *Decoder) callFee(ctx context.Context, poolAddress common.Address) (uint32, error) {
-	// fee() selector: 0xddca3f43
-	data := common.Hex2Bytes("ddca3f43")
-
 	msg := ethereum.CallMsg{
 		To:   &poolAddress,
-		Data: data,
+		Data: feeSelector,
 	}
 
 	result, err := d.client.CallContract(ctx, msg, nil)
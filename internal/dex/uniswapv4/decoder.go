@@ -0,0 +1,309 @@
+package uniswapv4
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+
+	"github.com/devlongs/mev-inspector/internal/dex"
+	"github.com/devlongs/mev-inspector/internal/eth"
+	"github.com/devlongs/mev-inspector/internal/metrics"
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+// Uniswap V4 Initialize event signature
+// event Initialize(bytes32 indexed id, address indexed currency0, address indexed currency1, uint24 fee, int24 tickSpacing, address hooks, uint160 sqrtPriceX96, int24 tick)
+var InitializeEventSignature = common.HexToHash("0xdd466e674ea557f56295e2d0218a125ea4b4f0f6f3307b95f85e6110838d6438")
+
+// Uniswap V4 Swap event signature
+// event Swap(bytes32 indexed id, address indexed sender, int128 amount0, int128 amount1, uint160 sqrtPriceX96, uint128 liquidity, int24 tick, uint24 fee)
+var SwapEventSignature = common.HexToHash("0x40e9cecb9f5f1f1c5b9c97dec2917b7ee92e57ba5563708daca94dd84ad7112f")
+
+// Uniswap V4 singleton PoolManager on mainnet
+var PoolManager = common.HexToAddress("0x000000000004444c5dc75cB358380D2e3dE08A90")
+
+// PoolManagerDeployBlock is the first block that can contain V4 events
+const PoolManagerDeployBlock = 21688329
+
+// V4 represents native ETH as the zero currency address
+var NativeETH = common.Address{}
+
+// WETH address on mainnet, substituted for NativeETH so V4 legs chain with other DEXes
+var WETH = common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+
+// unknownPoolTTL is how long a pool whose Initialize lookup came back empty
+// is treated as unknown. The answer may come from an endpoint that lags
+// behind the swap's block, so it is looked up again after a while.
+const unknownPoolTTL = time.Minute
+
+// ProtocolName identifies Uniswap V4 in config and decoded swaps
+const ProtocolName = "uniswap_v4"
+
+func init() {
+	dex.Register(ProtocolName, func(client eth.Reader) dex.ProtocolDecoder {
+		return NewDecoder(client)
+	})
+}
+
+// Decoder decodes Uniswap V4 swap events
+type Decoder struct {
+	client    eth.Reader
+	poolCache map[common.Hash]*PoolInfo
+	// Pools with no Initialize event, so their swaps can't be decoded, and
+	// when the lookup came back empty
+	unknownPools map[common.Hash]time.Time
+	mu           sync.RWMutex
+}
+
+// PoolInfo holds the pool key of a V4 pool, learned from its Initialize event
+type PoolInfo struct {
+	Currency0   common.Address
+	Currency1   common.Address
+	Fee         uint32
+	TickSpacing int32
+	Hooks       common.Address
+}
+
+// NewDecoder creates a new Uniswap V4 decoder
+func NewDecoder(client eth.Reader) *Decoder {
+	return &Decoder{
+		client:       client,
+		poolCache:    make(map[common.Hash]*PoolInfo),
+		unknownPools: make(map[common.Hash]time.Time),
+	}
+}
+
+// Name returns the protocol identifier
+func (d *Decoder) Name() string {
+	return ProtocolName
+}
+
+// EventSignatures returns the log topics handled by this decoder
+func (d *Decoder) EventSignatures() []common.Hash {
+	return []common.Hash{SwapEventSignature}
+}
+
+// GetSwapLogs fetches all Uniswap V4 swap logs in a block range. Initialize
+// events in the same range are indexed into the pool cache and not returned.
+func (d *Decoder) GetSwapLogs(ctx context.Context, fromBlock, toBlock uint64) ([]ethtypes.Log, error) {
+	query := ethereum.FilterQuery{
+		FromBlock: big.NewInt(int64(fromBlock)),
+		ToBlock:   big.NewInt(int64(toBlock)),
+		Addresses: []common.Address{PoolManager},
+		Topics: [][]common.Hash{
+			{InitializeEventSignature, SwapEventSignature},
+		},
+	}
+
+	logs, err := d.client.GetLogs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	swapLogs := make([]ethtypes.Log, 0, len(logs))
+	for _, l := range logs {
+		if len(l.Topics) == 0 {
+			continue
+		}
+		switch l.Topics[0] {
+		case InitializeEventSignature:
+			if err := d.indexInitialize(l); err != nil {
+				log.Warn().Err(err).Str("txHash", l.TxHash.Hex()).Msg("Failed to index V4 Initialize event")
+			}
+		case SwapEventSignature:
+			swapLogs = append(swapLogs, l)
+		}
+	}
+
+	return swapLogs, nil
+}
+
+// DecodeSwapLog decodes a single V4 swap log into a Swap struct
+func (d *Decoder) DecodeSwapLog(ctx context.Context, log ethtypes.Log) (*types.Swap, error) {
+	if len(log.Topics) < 3 {
+		return nil, fmt.Errorf("invalid swap log: expected 3 topics, got %d", len(log.Topics))
+	}
+
+	if log.Topics[0] != SwapEventSignature {
+		return nil, fmt.Errorf("not a Uniswap V4 swap event")
+	}
+
+	if log.Address != PoolManager {
+		return nil, fmt.Errorf("swap event not emitted by the Uniswap V4 PoolManager")
+	}
+
+	// Decode indexed parameters from topics
+	poolID := log.Topics[1]
+	sender := common.HexToAddress(log.Topics[2].Hex())
+
+	// Decode non-indexed parameters from data
+	// amount0 (int128), amount1 (int128), sqrtPriceX96 (uint160), liquidity (uint128), tick (int24), fee (uint24)
+	if len(log.Data) < 192 {
+		return nil, fmt.Errorf("invalid swap log data length: expected 192 bytes, got %d", len(log.Data))
+	}
+
+	amount0 := decodeSigned(log.Data[0:32])
+	amount1 := decodeSigned(log.Data[32:64])
+	sqrtPriceX96 := new(big.Int).SetBytes(log.Data[64:96])
+	liquidity := new(big.Int).SetBytes(log.Data[96:128])
+	tick := decodeSigned(log.Data[128:160])
+
+	// Get pool key (currency0, currency1, hooks)
+	poolInfo, err := d.getPoolInfo(ctx, poolID, log.BlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool info: %w", err)
+	}
+
+	// V4 amounts are balance deltas of the swapper: negative means the
+	// swapper paid the pool, positive means the swapper received
+	var amount0In, amount1In, amount0Out, amount1Out *big.Int
+
+	if amount0.Sign() < 0 {
+		amount0In = new(big.Int).Neg(amount0)
+		amount0Out = big.NewInt(0)
+	} else {
+		amount0In = big.NewInt(0)
+		amount0Out = new(big.Int).Set(amount0)
+	}
+
+	if amount1.Sign() < 0 {
+		amount1In = new(big.Int).Neg(amount1)
+		amount1Out = big.NewInt(0)
+	} else {
+		amount1In = big.NewInt(0)
+		amount1Out = new(big.Int).Set(amount1)
+	}
+
+	return &types.Swap{
+		TxHash:       log.TxHash,
+		BlockNumber:  log.BlockNumber,
+		LogIndex:     log.Index,
+		Pool:         PoolManager,
+		PoolID:       poolID,
+		Protocol:     ProtocolName,
+		Sender:       sender,
+		Recipient:    sender,
+		Token0:       currencyToToken(poolInfo.Currency0),
+		Token1:       currencyToToken(poolInfo.Currency1),
+		Amount0In:    amount0In,
+		Amount1In:    amount1In,
+		Amount0Out:   amount0Out,
+		Amount1Out:   amount1Out,
+		SqrtPriceX96: sqrtPriceX96,
+		Liquidity:    liquidity,
+		Tick:         tick,
+		Hooks:        poolInfo.Hooks,
+	}, nil
+}
+
+// getPoolInfo returns the cached pool key, falling back to a log query for
+// pools initialized before the inspector saw them. The query ends at the
+// swap's block, where the pool must already exist, so it has a fixed range
+// the client can split. Pools without an Initialize event are remembered for
+// unknownPoolTTL so their swaps don't repeat the scan.
+func (d *Decoder) getPoolInfo(ctx context.Context, poolID common.Hash, blockNumber uint64) (*PoolInfo, error) {
+	// Check cache first
+	d.mu.RLock()
+	info, ok := d.poolCache[poolID]
+	lookedUp, unknown := d.unknownPools[poolID]
+	d.mu.RUnlock()
+	unknown = unknown && time.Since(lookedUp) < unknownPoolTTL
+	metrics.PoolCacheLookup("uniswap_v4", ok || unknown)
+	if ok {
+		return info, nil
+	}
+	if unknown {
+		return nil, fmt.Errorf("no Initialize event for pool %s", poolID.Hex())
+	}
+
+	query := ethereum.FilterQuery{
+		FromBlock: big.NewInt(PoolManagerDeployBlock),
+		ToBlock:   new(big.Int).SetUint64(blockNumber),
+		Addresses: []common.Address{PoolManager},
+		Topics: [][]common.Hash{
+			{InitializeEventSignature},
+			{poolID},
+		},
+	}
+
+	logs, err := d.client.GetLogs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(logs) == 0 {
+		d.mu.Lock()
+		d.unknownPools[poolID] = time.Now()
+		d.mu.Unlock()
+		return nil, fmt.Errorf("no Initialize event for pool %s", poolID.Hex())
+	}
+
+	if err := d.indexInitialize(logs[0]); err != nil {
+		return nil, err
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.poolCache[poolID], nil
+}
+
+// indexInitialize decodes an Initialize event and caches its pool key
+func (d *Decoder) indexInitialize(initLog ethtypes.Log) error {
+	if len(initLog.Topics) < 4 {
+		return fmt.Errorf("invalid initialize log: expected 4 topics, got %d", len(initLog.Topics))
+	}
+
+	// fee (uint24), tickSpacing (int24), hooks (address), sqrtPriceX96 (uint160), tick (int24)
+	if len(initLog.Data) < 160 {
+		return fmt.Errorf("invalid initialize log data length: expected 160 bytes, got %d", len(initLog.Data))
+	}
+
+	poolID := initLog.Topics[1]
+	info := &PoolInfo{
+		Currency0:   common.HexToAddress(initLog.Topics[2].Hex()),
+		Currency1:   common.HexToAddress(initLog.Topics[3].Hex()),
+		Fee:         uint32(new(big.Int).SetBytes(initLog.Data[0:32]).Uint64()),
+		TickSpacing: int32(decodeSigned(initLog.Data[32:64]).Int64()),
+		Hooks:       common.BytesToAddress(initLog.Data[76:96]),
+	}
+
+	// Cache the result
+	d.mu.Lock()
+	d.poolCache[poolID] = info
+	delete(d.unknownPools, poolID)
+	d.mu.Unlock()
+
+	log.Debug().
+		Str("poolId", poolID.Hex()).
+		Str("currency0", info.Currency0.Hex()).
+		Str("currency1", info.Currency1.Hex()).
+		Uint32("fee", info.Fee).
+		Str("hooks", info.Hooks.Hex()).
+		Msg("Cached V4 pool key")
+
+	return nil
+}
+
+// decodeSigned decodes a two's complement ABI word into a signed integer
+func decodeSigned(word []byte) *big.Int {
+	value := new(big.Int).SetBytes(word)
+	if word[0]&0x80 != 0 {
+		value.Sub(value, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+	return value
+}
+
+// currencyToToken maps the native ETH currency to WETH
+func currencyToToken(currency common.Address) common.Address {
+	if currency == NativeETH {
+		return WETH
+	}
+	return currency
+}
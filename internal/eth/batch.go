@@ -0,0 +1,204 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// CallResult is the outcome of one contract call in a batch or multicall
+type CallResult struct {
+	Data []byte
+	Err  error
+}
+
+// batchCall sends elems as JSON-RPC batch requests of at most MaxBatchSize
+// calls each, with retry. Errors of individual calls are left in each
+// element's Error field.
+func (c *Client) batchCall(ctx context.Context, elems []rpc.BatchElem) error {
+	size := c.cfg.MaxBatchSize
+	if size < 1 {
+		size = len(elems)
+	}
+
+	for start := 0; start < len(elems); start += size {
+		batch := elems[start:min(start+size, len(elems))]
+
+		_, err := call(ctx, c, "batch", false, func(ctx context.Context, ec *ethclient.Client) (struct{}, error) {
+			for idx := range batch {
+				batch[idx].Error = nil
+			}
+			return struct{}{}, ec.Client().BatchCallContext(ctx, batch)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to send batch after %d attempts: %w", c.cfg.RetryAttempts, err)
+		}
+	}
+
+	return nil
+}
+
+// TransactionAndReceipt fetches a transaction and its receipt in a single
+// round trip
+func (c *Client) TransactionAndReceipt(ctx context.Context, txHash common.Hash) (*types.Transaction, *types.Receipt, error) {
+	var tx, receipt json.RawMessage
+	elems := []rpc.BatchElem{
+		{Method: "eth_getTransactionByHash", Args: []interface{}{txHash}, Result: &tx},
+		{Method: "eth_getTransactionReceipt", Args: []interface{}{txHash}, Result: &receipt},
+	}
+
+	if err := c.batchCall(ctx, elems); err != nil {
+		return nil, nil, err
+	}
+	for _, elem := range elems {
+		if elem.Error != nil {
+			return nil, nil, fmt.Errorf("failed to call %s: %w", elem.Method, elem.Error)
+		}
+	}
+
+	if isNull(tx) || isNull(receipt) {
+		return nil, nil, ethereum.NotFound
+	}
+
+	var t types.Transaction
+	if err := json.Unmarshal(tx, &t); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	var r types.Receipt
+	if err := json.Unmarshal(receipt, &r); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode receipt: %w", err)
+	}
+
+	return &t, &r, nil
+}
+
+// BlockHeader is a block header along with the hash the node reported for
+// it. Header.Hash() recomputes the hash from the fields go-ethereum knows
+// about, which misses fields added by later forks, so block identity always
+// comes from the node.
+type BlockHeader struct {
+	*types.Header
+	Hash common.Hash
+}
+
+// HeaderRange fetches the headers of blocks from through to, in order, in
+// JSON-RPC batches
+func (c *Client) HeaderRange(ctx context.Context, from, to uint64) ([]*BlockHeader, error) {
+	if to < from {
+		return nil, nil
+	}
+
+	raw := make([]json.RawMessage, to-from+1)
+	elems := make([]rpc.BatchElem, len(raw))
+	for idx := range elems {
+		elems[idx] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{hexutil.EncodeUint64(from + uint64(idx)), false},
+			Result: &raw[idx],
+		}
+	}
+
+	if err := c.batchCall(ctx, elems); err != nil {
+		return nil, err
+	}
+
+	headers := make([]*BlockHeader, len(raw))
+	for idx, elem := range elems {
+		if elem.Error != nil {
+			return nil, fmt.Errorf("failed to get header %d: %w", from+uint64(idx), elem.Error)
+		}
+		header, err := decodeBlockHeader(raw[idx])
+		if err != nil {
+			return nil, fmt.Errorf("failed to get header %d: %w", from+uint64(idx), err)
+		}
+		headers[idx] = header
+	}
+
+	return headers, nil
+}
+
+// decodeBlockHeader decodes an eth_getBlockByNumber result into a header and
+// its node-reported hash
+func decodeBlockHeader(raw json.RawMessage) (*BlockHeader, error) {
+	if isNull(raw) {
+		return nil, ethereum.NotFound
+	}
+
+	var header types.Header
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return nil, fmt.Errorf("failed to decode header: %w", err)
+	}
+	var id struct {
+		Hash *common.Hash `json:"hash"`
+	}
+	if err := json.Unmarshal(raw, &id); err != nil {
+		return nil, fmt.Errorf("failed to decode block hash: %w", err)
+	}
+	if id.Hash == nil {
+		return nil, fmt.Errorf("block has no hash")
+	}
+
+	return &BlockHeader{Header: &header, Hash: *id.Hash}, nil
+}
+
+// CallContracts executes contract calls in JSON-RPC batches
+func (c *Client) CallContracts(ctx context.Context, msgs []ethereum.CallMsg, blockNumber *big.Int) ([]CallResult, error) {
+	data := make([]hexutil.Bytes, len(msgs))
+	elems := make([]rpc.BatchElem, len(msgs))
+	for idx, msg := range msgs {
+		elems[idx] = rpc.BatchElem{
+			Method: "eth_call",
+			Args:   []interface{}{toCallArg(msg), toBlockNumArg(blockNumber)},
+			Result: &data[idx],
+		}
+	}
+
+	if err := c.batchCall(ctx, elems); err != nil {
+		return nil, err
+	}
+
+	results := make([]CallResult, len(msgs))
+	for idx, elem := range elems {
+		results[idx] = CallResult{Data: data[idx], Err: elem.Error}
+	}
+	return results, nil
+}
+
+// toCallArg encodes a call message the way eth_call expects it
+func toCallArg(msg ethereum.CallMsg) interface{} {
+	arg := map[string]interface{}{
+		"from": msg.From,
+		"to":   msg.To,
+	}
+	if len(msg.Data) > 0 {
+		arg["input"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	return arg
+}
+
+// toBlockNumArg encodes a block number, nil meaning the latest block
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	return hexutil.EncodeBig(number)
+}
+
+// isNull reports whether a raw JSON-RPC result is empty or null
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
@@ -2,12 +2,16 @@ package eth
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
-	"time"
+	"net/http"
+	"sync"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/rs/zerolog/log"
@@ -15,43 +19,120 @@ import (
 	"github.com/devlongs/mev-inspector/internal/config"
 )
 
-// Client wraps the Ethereum client with retry logic and convenience methods
+// Client wraps one or more Ethereum RPC endpoints with load balancing,
+// failover, retry logic and convenience methods
 type Client struct {
-	client  *ethclient.Client
-	cfg     config.RPCConfig
-	chainID *big.Int
+	endpoints []*endpoint
+	cfg       config.RPCConfig
+	chainID   *big.Int
+	logRange  rangeLimiter
+
+	stop context.CancelFunc // Stops the health checks
+	wg   sync.WaitGroup
 }
 
-// NewClient creates a new Ethereum client
-func NewClient(cfg config.RPCConfig) (*Client, error) {
-	client, err := ethclient.Dial(cfg.URL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Ethereum node: %w", err)
+// Option customizes a Client
+type Option func(*options)
+
+type options struct {
+	transport http.RoundTripper
+}
+
+// WithTransport sends HTTP JSON-RPC requests through transport, e.g. to
+// record or replay them
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = transport
 	}
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), cfg.RequestTimeout)
-	defer cancel()
+// NewClient creates a new Ethereum client for the configured endpoints, or
+// for rpc.url if none are configured
+func NewClient(cfg config.RPCConfig, opts ...Option) (*Client, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
 
-	chainID, err := client.ChainID(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get chain ID: %w", err)
+	endpointCfgs := cfg.Endpoints
+	if len(endpointCfgs) == 0 {
+		endpointCfgs = []config.EndpointConfig{{URL: cfg.URL}}
 	}
 
-	log.Info().
-		Str("url", cfg.URL).
-		Str("chainID", chainID.String()).
-		Msg("Connected to Ethereum node")
+	c := &Client{cfg: cfg}
+	for _, epCfg := range endpointCfgs {
+		ep, err := newEndpoint(epCfg, o)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to connect to Ethereum node %s: %w", endpointName(epCfg.URL), err)
+		}
+		c.endpoints = append(c.endpoints, ep)
+	}
+
+	if err := c.checkChainID(); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	// Health checks only matter when there is another endpoint to switch to
+	if len(c.endpoints) > 1 && cfg.HealthCheckInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		c.stop = cancel
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.healthCheckLoop(ctx)
+		}()
+	}
+
+	return c, nil
+}
+
+// checkChainID fetches the chain ID from every endpoint. Endpoints that are
+// down start out of rotation; endpoints on a different chain are an error.
+func (c *Client) checkChainID() error {
+	var lastErr error
 
-	return &Client{
-		client:  client,
-		cfg:     cfg,
-		chainID: chainID,
-	}, nil
+	for _, ep := range c.endpoints {
+		ctx, cancel := context.WithTimeout(context.Background(), c.cfg.RequestTimeout)
+		chainID, err := invoke(ctx, ep, "eth_chainId", func(ctx context.Context, ec *ethclient.Client) (*big.Int, error) {
+			return ec.ChainID(ctx)
+		})
+		cancel()
+
+		if err != nil {
+			lastErr = err
+			ep.setHealth(0, err)
+			continue
+		}
+
+		if c.chainID == nil {
+			c.chainID = chainID
+		} else if c.chainID.Cmp(chainID) != 0 {
+			return fmt.Errorf("endpoint %s is on chain %s, expected %s", ep.name, chainID, c.chainID)
+		}
+
+		log.Info().
+			Str("endpoint", ep.name).
+			Str("chainID", chainID.String()).
+			Msg("Connected to Ethereum node")
+	}
+
+	if c.chainID == nil {
+		return fmt.Errorf("failed to get chain ID: %w", lastErr)
+	}
+	return nil
 }
 
-// Close closes the client connection
+// Close stops the health checks and closes every endpoint connection
 func (c *Client) Close() {
-	c.client.Close()
+	if c.stop != nil {
+		c.stop()
+		c.wg.Wait()
+	}
+	for _, ep := range c.endpoints {
+		ep.client.Close()
+	}
 }
 
 // ChainID returns the chain ID
@@ -59,110 +140,137 @@ func (c *Client) ChainID() *big.Int {
 	return c.chainID
 }
 
-// BlockNumber returns the latest block number with retry
+// BlockNumber returns the latest block number with retry. Since calls for
+// the returned blocks may go to any preferred endpoint, it is capped at the
+// lowest head among them.
 func (c *Client) BlockNumber(ctx context.Context) (uint64, error) {
-	var blockNum uint64
-	var err error
-
-	for i := 0; i < c.cfg.RetryAttempts; i++ {
-		blockNum, err = c.client.BlockNumber(ctx)
+	blockNum, err := callHighest(ctx, c, "eth_blockNumber", func(ctx context.Context, ec *ethclient.Client) (uint64, error) {
+		head, err := ec.BlockNumber(ctx)
 		if err == nil {
-			return blockNum, nil
+			c.endpointOf(ec).observeHead(head)
 		}
-		log.Warn().Err(err).Int("attempt", i+1).Msg("Failed to get block number, retrying...")
-		time.Sleep(c.cfg.RetryDelay)
+		return head, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get block number after %d attempts: %w", c.cfg.RetryAttempts, err)
 	}
 
-	return 0, fmt.Errorf("failed to get block number after %d attempts: %w", c.cfg.RetryAttempts, err)
+	if synced, ok := c.syncedHead(); ok && synced < blockNum {
+		return synced, nil
+	}
+	return blockNum, nil
 }
 
 // BlockByNumber returns a block by number with retry
 func (c *Client) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
-	var block *types.Block
-	var err error
-
-	for i := 0; i < c.cfg.RetryAttempts; i++ {
-		block, err = c.client.BlockByNumber(ctx, number)
-		if err == nil {
-			return block, nil
-		}
-		log.Warn().Err(err).Int("attempt", i+1).Msg("Failed to get block, retrying...")
-		time.Sleep(c.cfg.RetryDelay)
+	block, err := call(ctx, c, "eth_getBlockByNumber", false, func(ctx context.Context, ec *ethclient.Client) (*types.Block, error) {
+		return ec.BlockByNumber(ctx, number)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block after %d attempts: %w", c.cfg.RetryAttempts, err)
 	}
-
-	return nil, fmt.Errorf("failed to get block after %d attempts: %w", c.cfg.RetryAttempts, err)
+	return block, nil
 }
 
-// GetLogs fetches logs with the given filter with retry
-func (c *Client) GetLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
-	var logs []types.Log
-	var err error
+// HeaderByNumber returns a block header by number with retry
+func (c *Client) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	header, err := call(ctx, c, "eth_getBlockByNumber", true, func(ctx context.Context, ec *ethclient.Client) (*types.Header, error) {
+		return ec.HeaderByNumber(ctx, number)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get header after %d attempts: %w", c.cfg.RetryAttempts, err)
+	}
+	return header, nil
+}
 
-	for i := 0; i < c.cfg.RetryAttempts; i++ {
-		logs, err = c.client.FilterLogs(ctx, query)
-		if err == nil {
-			return logs, nil
+// BlockHeaderByNumber returns a block header and its node-reported hash by
+// number with retry
+func (c *Client) BlockHeaderByNumber(ctx context.Context, number uint64) (*BlockHeader, error) {
+	header, err := call(ctx, c, "eth_getBlockByNumber", true, func(ctx context.Context, ec *ethclient.Client) (*BlockHeader, error) {
+		var raw json.RawMessage
+		if err := ec.Client().CallContext(ctx, &raw, "eth_getBlockByNumber", hexutil.EncodeUint64(number), false); err != nil {
+			return nil, err
 		}
-		log.Warn().Err(err).Int("attempt", i+1).Msg("Failed to get logs, retrying...")
-		time.Sleep(c.cfg.RetryDelay)
+		return decodeBlockHeader(raw)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get header after %d attempts: %w", c.cfg.RetryAttempts, err)
 	}
-
-	return nil, fmt.Errorf("failed to get logs after %d attempts: %w", c.cfg.RetryAttempts, err)
+	return header, nil
 }
 
 // TransactionReceipt returns the receipt of a transaction with retry
 func (c *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
-	var receipt *types.Receipt
-	var err error
-
-	for i := 0; i < c.cfg.RetryAttempts; i++ {
-		receipt, err = c.client.TransactionReceipt(ctx, txHash)
-		if err == nil {
-			return receipt, nil
-		}
-		log.Warn().Err(err).Int("attempt", i+1).Msg("Failed to get receipt, retrying...")
-		time.Sleep(c.cfg.RetryDelay)
+	receipt, err := call(ctx, c, "eth_getTransactionReceipt", true, func(ctx context.Context, ec *ethclient.Client) (*types.Receipt, error) {
+		return ec.TransactionReceipt(ctx, txHash)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get receipt after %d attempts: %w", c.cfg.RetryAttempts, err)
 	}
+	return receipt, nil
+}
 
-	return nil, fmt.Errorf("failed to get receipt after %d attempts: %w", c.cfg.RetryAttempts, err)
+// txLookup is the result of eth_getTransactionByHash
+type txLookup struct {
+	tx        *types.Transaction
+	isPending bool
 }
 
 // GetTransaction returns a transaction by hash with retry
 func (c *Client) GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
-	var tx *types.Transaction
-	var isPending bool
-	var err error
-
-	for i := 0; i < c.cfg.RetryAttempts; i++ {
-		tx, isPending, err = c.client.TransactionByHash(ctx, txHash)
-		if err == nil {
-			return tx, isPending, nil
-		}
-		log.Warn().Err(err).Int("attempt", i+1).Msg("Failed to get transaction, retrying...")
-		time.Sleep(c.cfg.RetryDelay)
+	result, err := call(ctx, c, "eth_getTransactionByHash", true, func(ctx context.Context, ec *ethclient.Client) (txLookup, error) {
+		tx, isPending, err := ec.TransactionByHash(ctx, txHash)
+		return txLookup{tx: tx, isPending: isPending}, err
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get transaction after %d attempts: %w", c.cfg.RetryAttempts, err)
 	}
-
-	return nil, false, fmt.Errorf("failed to get transaction after %d attempts: %w", c.cfg.RetryAttempts, err)
+	return result.tx, result.isPending, nil
 }
 
 // CallContract executes a contract call with retry
 func (c *Client) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
-	var result []byte
-	var err error
+	result, err := call(ctx, c, "eth_call", true, func(ctx context.Context, ec *ethclient.Client) ([]byte, error) {
+		return ec.CallContract(ctx, msg, blockNumber)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call contract after %d attempts: %w", c.cfg.RetryAttempts, err)
+	}
+	return result, nil
+}
 
-	for i := 0; i < c.cfg.RetryAttempts; i++ {
-		result, err = c.client.CallContract(ctx, msg, blockNumber)
-		if err == nil {
-			return result, nil
-		}
-		log.Warn().Err(err).Int("attempt", i+1).Msg("Failed to call contract, retrying...")
-		time.Sleep(c.cfg.RetryDelay)
+// ErrNoWebSocket is returned by SubscribeNewHead when rpc.ws_url is not set
+var ErrNoWebSocket = errors.New("no WebSocket URL configured")
+
+// SubscribeNewHead subscribes to new block headers over rpc.ws_url. Each
+// subscription has its own connection, which is closed on Unsubscribe.
+func (c *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	if c.cfg.WSUrl == "" {
+		return nil, ErrNoWebSocket
+	}
+
+	ws, err := ethclient.DialContext(ctx, c.cfg.WSUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", endpointName(c.cfg.WSUrl), err)
+	}
+
+	sub, err := ws.SubscribeNewHead(ctx, ch)
+	if err != nil {
+		ws.Close()
+		return nil, fmt.Errorf("failed to subscribe to new heads: %w", err)
 	}
 
-	return nil, fmt.Errorf("failed to call contract after %d attempts: %w", c.cfg.RetryAttempts, err)
+	return &wsSubscription{Subscription: sub, client: ws}, nil
 }
 
-// SubscribeNewHead subscribes to new block headers (requires WebSocket)
-func (c *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
-	return c.client.SubscribeNewHead(ctx, ch)
+// wsSubscription closes its connection when unsubscribed
+type wsSubscription struct {
+	ethereum.Subscription
+	client *ethclient.Client
+}
+
+// Unsubscribe cancels the subscription and closes the connection
+func (s *wsSubscription) Unsubscribe() {
+	s.Subscription.Unsubscribe()
+	s.client.Close()
 }
@@ -0,0 +1,430 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
+
+	"github.com/devlongs/mev-inspector/internal/config"
+	"github.com/devlongs/mev-inspector/internal/metrics"
+)
+
+// Consecutive failed calls before an endpoint is taken out of rotation until
+// the next successful health check
+const maxEndpointFailures = 3
+
+// endpoint is one RPC provider the client balances between
+type endpoint struct {
+	name    string
+	url     string
+	weight  int
+	client  *ethclient.Client
+	limiter *rate.Limiter // nil = unlimited
+
+	healthy  bool
+	head     uint64 // Block height at the last health check
+	failures int    // Consecutive failed calls
+	mu       sync.Mutex
+}
+
+// newEndpoint dials one configured provider
+func newEndpoint(cfg config.EndpointConfig, opts options) (*endpoint, error) {
+	var dialOpts []rpc.ClientOption
+	if opts.transport != nil {
+		dialOpts = append(dialOpts, rpc.WithHTTPClient(&http.Client{Transport: opts.transport}))
+	}
+
+	rpcClient, err := rpc.DialOptions(context.Background(), cfg.URL, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	client := ethclient.NewClient(rpcClient)
+
+	ep := &endpoint{
+		name:    cfg.Name,
+		url:     cfg.URL,
+		weight:  cfg.Weight,
+		client:  client,
+		healthy: true,
+	}
+	if ep.name == "" {
+		ep.name = endpointName(cfg.URL)
+	}
+	if ep.weight < 1 {
+		ep.weight = 1
+	}
+	if cfg.RateLimit > 0 {
+		burst := int(cfg.RateLimit)
+		if burst < 1 {
+			burst = 1
+		}
+		ep.limiter = rate.NewLimiter(rate.Limit(cfg.RateLimit), burst)
+	}
+
+	return ep, nil
+}
+
+// endpointName derives a label from a URL without leaking API keys, which
+// providers usually put in the path or query
+func endpointName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "endpoint"
+	}
+	return u.Host
+}
+
+// record updates the endpoint's failure count after a call
+func (ep *endpoint) record(err error) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	if !isEndpointFault(err) {
+		ep.failures = 0
+		return
+	}
+
+	ep.failures++
+	if ep.failures == maxEndpointFailures && ep.healthy {
+		ep.healthy = false
+		metrics.RPCEndpointHealthy.WithLabelValues(ep.name).Set(0)
+		log.Warn().Err(err).Str("endpoint", ep.name).Msg("RPC endpoint failing, taking it out of rotation")
+	}
+}
+
+// setHealth records the outcome of a health check
+func (ep *endpoint) setHealth(head uint64, err error) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	wasHealthy := ep.healthy
+	ep.healthy = err == nil
+	if err == nil {
+		ep.head = head
+		ep.failures = 0
+		metrics.RPCEndpointHead.WithLabelValues(ep.name).Set(float64(head))
+	}
+
+	if ep.healthy {
+		metrics.RPCEndpointHealthy.WithLabelValues(ep.name).Set(1)
+	} else {
+		metrics.RPCEndpointHealthy.WithLabelValues(ep.name).Set(0)
+	}
+
+	switch {
+	case wasHealthy && !ep.healthy:
+		log.Warn().Err(err).Str("endpoint", ep.name).Msg("RPC endpoint failed health check")
+	case !wasHealthy && ep.healthy:
+		log.Info().Str("endpoint", ep.name).Uint64("head", head).Msg("RPC endpoint recovered")
+	}
+}
+
+// observeHead raises the endpoint's known head after it reported a newer
+// block number
+func (ep *endpoint) observeHead(head uint64) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	if head > ep.head {
+		ep.head = head
+		metrics.RPCEndpointHead.WithLabelValues(ep.name).Set(float64(head))
+	}
+}
+
+// state returns the endpoint's health and last known head
+func (ep *endpoint) state() (bool, uint64) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	return ep.healthy, ep.head
+}
+
+// isEndpointFault reports whether err says something about the endpoint
+// rather than about the request
+func isEndpointFault(err error) bool {
+	if err == nil ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, ethereum.NotFound) ||
+		IsRangeLimitError(err) ||
+		IsRevertError(err) {
+		return false
+	}
+	return true
+}
+
+// bestHead returns the highest head among the healthy endpoints
+func (c *Client) bestHead() uint64 {
+	var best uint64
+	for _, ep := range c.endpoints {
+		if healthy, head := ep.state(); healthy && head > best {
+			best = head
+		}
+	}
+	return best
+}
+
+// syncedHead returns the lowest head among the endpoints pick prefers, so
+// every block up to it can be fetched from whichever of them a call is sent
+// to. ok is false if no endpoint is preferred.
+func (c *Client) syncedHead() (head uint64, ok bool) {
+	best := c.bestHead()
+	for _, ep := range c.endpoints {
+		healthy, epHead := ep.state()
+		if !healthy || epHead+c.cfg.MaxBlockLag < best {
+			continue
+		}
+		if !ok || epHead < head {
+			head = epHead
+			ok = true
+		}
+	}
+	return head, ok
+}
+
+// endpointOf returns the endpoint that owns ec
+func (c *Client) endpointOf(ec *ethclient.Client) *endpoint {
+	for _, ep := range c.endpoints {
+		if ep.client == ec {
+			return ep
+		}
+	}
+	return nil
+}
+
+// pick chooses an endpoint that hasn't been tried yet, weighted by its share
+// of requests. Healthy endpoints within MaxBlockLag of the best known head
+// are preferred; if there are none every untried endpoint is a candidate.
+// Returns nil once every endpoint has been tried.
+func (c *Client) pick(tried map[*endpoint]bool) *endpoint {
+	best := c.bestHead()
+
+	var preferred, fallback []*endpoint
+	for _, ep := range c.endpoints {
+		if tried[ep] {
+			continue
+		}
+		fallback = append(fallback, ep)
+
+		healthy, head := ep.state()
+		if healthy && head+c.cfg.MaxBlockLag >= best {
+			preferred = append(preferred, ep)
+		}
+	}
+
+	if len(preferred) > 0 {
+		return pickWeighted(preferred)
+	}
+	if len(fallback) > 0 {
+		return pickWeighted(fallback)
+	}
+	return nil
+}
+
+// pickWeighted picks one of endpoints at random in proportion to its weight
+func pickWeighted(endpoints []*endpoint) *endpoint {
+	total := 0
+	for _, ep := range endpoints {
+		total += ep.weight
+	}
+
+	n := rand.Intn(total)
+	for _, ep := range endpoints {
+		if n < ep.weight {
+			return ep
+		}
+		n -= ep.weight
+	}
+	return endpoints[len(endpoints)-1]
+}
+
+// healthCheckLoop polls every endpoint's block height until ctx is cancelled
+func (c *Client) healthCheckLoop(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		c.checkHealth(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkHealth fetches the block height of every endpoint and logs the ones
+// that fell behind
+func (c *Client) checkHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, ep := range c.endpoints {
+		wg.Add(1)
+		go func(ep *endpoint) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, c.cfg.RequestTimeout)
+			defer cancel()
+
+			head, err := invoke(checkCtx, ep, "eth_blockNumber", func(ctx context.Context, ec *ethclient.Client) (uint64, error) {
+				return ec.BlockNumber(ctx)
+			})
+			if ctx.Err() != nil {
+				return
+			}
+			ep.setHealth(head, err)
+		}(ep)
+	}
+	wg.Wait()
+
+	best := c.bestHead()
+	for _, ep := range c.endpoints {
+		if healthy, head := ep.state(); healthy && head+c.cfg.MaxBlockLag < best {
+			log.Warn().
+				Str("endpoint", ep.name).
+				Uint64("head", head).
+				Uint64("best", best).
+				Msg("RPC endpoint is lagging, avoiding it")
+		}
+	}
+}
+
+// invoke makes one call on an endpoint, waiting for its rate limit
+func invoke[T any](ctx context.Context, ep *endpoint, method string, fn func(context.Context, *ethclient.Client) (T, error)) (T, error) {
+	if ep.limiter != nil {
+		if err := ep.limiter.Wait(ctx); err != nil {
+			var zero T
+			return zero, err
+		}
+	}
+
+	start := time.Now()
+	value, err := fn(ctx, ep.client)
+	metrics.ObserveRPC(method, start, err)
+	ep.record(err)
+
+	return value, err
+}
+
+// callResult is the outcome of a call on one endpoint
+type callResult[T any] struct {
+	value T
+	err   error
+}
+
+// call runs fn with retry, failing over to another endpoint after every
+// error. Once every endpoint has failed it waits RetryDelay before starting
+// over. With hedge set and HedgeDelay configured, each attempt is also sent
+// to a second endpoint if the first hasn't answered in time, and the first
+// response wins.
+func call[T any](ctx context.Context, c *Client, method string, hedge bool, fn func(context.Context, *ethclient.Client) (T, error)) (T, error) {
+	return retry(ctx, c, method, hedge, nil, fn)
+}
+
+// callHighest is a hedged call that waits for both responses of a hedge and
+// returns the higher one, for block numbers where the fastest endpoint may
+// be behind
+func callHighest(ctx context.Context, c *Client, method string, fn func(context.Context, *ethclient.Client) (uint64, error)) (uint64, error) {
+	return retry(ctx, c, method, true, func(a, b uint64) bool { return a > b }, fn)
+}
+
+// retry implements call. With better set, an attempt returns the best
+// successful response instead of the first.
+func retry[T any](ctx context.Context, c *Client, method string, hedge bool, better func(a, b T) bool, fn func(context.Context, *ethclient.Client) (T, error)) (T, error) {
+	var value T
+	var err error
+	tried := make(map[*endpoint]bool)
+
+	for i := 0; i < c.cfg.RetryAttempts; i++ {
+		if len(tried) == len(c.endpoints) {
+			tried = make(map[*endpoint]bool)
+		}
+
+		value, err = attempt(ctx, c, method, hedge, better, tried, fn)
+		if err == nil {
+			return value, nil
+		}
+		// None of these gets better by asking again
+		if ctx.Err() != nil || IsRangeLimitError(err) || IsRevertError(err) {
+			return value, err
+		}
+
+		log.Warn().Err(err).Str("method", method).Int("attempt", i+1).Msg("RPC call failed, retrying...")
+		if len(tried) == len(c.endpoints) {
+			time.Sleep(c.cfg.RetryDelay)
+		}
+	}
+
+	return value, err
+}
+
+// attempt makes one, possibly hedged, call and returns the first success, or
+// with better set the best one, or the last error
+func attempt[T any](ctx context.Context, c *Client, method string, hedge bool, better func(a, b T) bool, tried map[*endpoint]bool, fn func(context.Context, *ethclient.Client) (T, error)) (T, error) {
+	// The losing request of a hedge is cancelled on return
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan callResult[T], 2)
+	launch := func() bool {
+		ep := c.pick(tried)
+		if ep == nil {
+			return false
+		}
+		tried[ep] = true
+
+		go func() {
+			value, err := invoke(ctx, ep, method, fn)
+			results <- callResult[T]{value: value, err: err}
+		}()
+		return true
+	}
+
+	launch()
+	pending := 1
+
+	var hedgeTimer <-chan time.Time
+	if hedge && c.cfg.HedgeDelay > 0 && len(tried) < len(c.endpoints) {
+		timer := time.NewTimer(c.cfg.HedgeDelay)
+		defer timer.Stop()
+		hedgeTimer = timer.C
+	}
+
+	var result callResult[T]
+	var best *T
+	for pending > 0 {
+		select {
+		case result = <-results:
+			pending--
+			if result.err != nil {
+				continue
+			}
+			if better == nil {
+				return result.value, nil
+			}
+			if best == nil || better(result.value, *best) {
+				value := result.value
+				best = &value
+			}
+		case <-hedgeTimer:
+			hedgeTimer = nil
+			if launch() {
+				pending++
+				metrics.RPCHedgedRequests.WithLabelValues(method).Inc()
+			}
+		}
+	}
+
+	if best != nil {
+		return *best, nil
+	}
+	return result.value, result.err
+}
@@ -0,0 +1,47 @@
+package eth
+
+import (
+	"errors"
+	"strings"
+)
+
+// rangeLimitMessages are fragments of the errors providers return when an
+// eth_getLogs query covers too many blocks or matches too many logs
+var rangeLimitMessages = []string{
+	"query returned more than",   // Infura, geth-based providers
+	"log response size exceeded", // Alchemy
+	"exceed maximum block range", // Alchemy, QuickNode
+	"block range is too wide",    // Ankr
+	"block range too large",
+	"range is too large",
+	"too many results",
+	"response size exceeded",
+	"max results",
+}
+
+// IsRangeLimitError reports whether err is a provider rejecting an
+// eth_getLogs query for being too large. Retrying such a query unchanged
+// never succeeds; the range has to be split.
+func IsRangeLimitError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range rangeLimitMessages {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRevertError reports whether err is a call reverting on chain. The
+// result is a property of the call at that block, so retrying it or sending
+// it to another endpoint gives the same answer.
+func IsRevertError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, errCallFailed) || strings.Contains(strings.ToLower(err.Error()), "execution reverted")
+}
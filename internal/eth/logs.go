@@ -0,0 +1,160 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/rs/zerolog/log"
+
+	"github.com/devlongs/mev-inspector/internal/metrics"
+)
+
+// Successful full-size queries before the learned range limit is raised again
+const rangeGrowAfter = 10
+
+// rangeLimiter learns how many blocks the provider accepts in one
+// eth_getLogs query. The limit is halved whenever a range is rejected and
+// doubled again after a run of successful queries, since limits on the
+// number of results depend on how busy the blocks are.
+type rangeLimiter struct {
+	limit  uint64 // 0 = no known limit
+	streak int
+	mu     sync.Mutex
+}
+
+// get returns the current limit, or 0 if there is none
+func (r *rangeLimiter) get() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.limit
+}
+
+// rejected lowers the limit after a range of size blocks was rejected
+func (r *rangeLimiter) rejected(size uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	half := size / 2
+	if half < 1 {
+		half = 1
+	}
+	if r.limit == 0 || half < r.limit {
+		r.limit = half
+	}
+	r.streak = 0
+}
+
+// succeeded raises the limit after a run of queries that used all of it
+func (r *rangeLimiter) succeeded(size uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.limit == 0 || size < r.limit {
+		return
+	}
+
+	r.streak++
+	if r.streak >= rangeGrowAfter {
+		r.limit *= 2
+		r.streak = 0
+	}
+}
+
+// GetLogs fetches logs with the given filter with retry. Block ranges the
+// provider rejects as too large or as returning too many results are split
+// in half until they are accepted and the results are merged in order; if
+// even a single block is rejected an error is returned.
+func (c *Client) GetLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	// Only explicit block ranges can be split
+	if query.BlockHash != nil || query.FromBlock == nil || query.ToBlock == nil {
+		return c.filterLogs(ctx, query)
+	}
+
+	from, to := query.FromBlock.Uint64(), query.ToBlock.Uint64()
+	if to < from {
+		return c.filterLogs(ctx, query)
+	}
+
+	var logs []types.Log
+	for from <= to {
+		// Don't send ranges the provider is known to reject
+		end := to
+		if limit := c.logRange.get(); limit > 0 && end-from+1 > limit {
+			end = from + limit - 1
+		}
+
+		chunk, err := c.getLogsRange(ctx, query, from, end)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, chunk...)
+
+		if end == to {
+			break
+		}
+		from = end + 1
+	}
+
+	return logs, nil
+}
+
+// getLogsRange fetches the logs of blocks from..to, bisecting the range
+// while the provider rejects it
+func (c *Client) getLogsRange(ctx context.Context, query ethereum.FilterQuery, from, to uint64) ([]types.Log, error) {
+	query.FromBlock = new(big.Int).SetUint64(from)
+	query.ToBlock = new(big.Int).SetUint64(to)
+
+	logs, err := c.filterLogs(ctx, query)
+	if err == nil {
+		c.logRange.succeeded(to - from + 1)
+		return logs, nil
+	}
+	if !IsRangeLimitError(err) {
+		return nil, err
+	}
+	if from == to {
+		return nil, fmt.Errorf("provider rejected logs of block %d even on its own: %w", from, err)
+	}
+
+	c.logRange.rejected(to - from + 1)
+	metrics.LogRangeSplits.Inc()
+
+	mid := from + (to-from)/2
+	log.Debug().
+		Uint64("from", from).
+		Uint64("to", to).
+		Uint64("mid", mid).
+		Msg("Log range rejected by provider, splitting")
+
+	left, err := c.getLogsRange(ctx, query, from, mid)
+	if err != nil {
+		return nil, err
+	}
+	right, err := c.getLogsRange(ctx, query, mid+1, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(left, right...), nil
+}
+
+// filterLogs issues a single eth_getLogs query with retry. Range limit
+// errors are returned immediately since retrying the same range can't
+// succeed.
+func (c *Client) filterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	logs, err := call(ctx, c, "eth_getLogs", false, func(ctx context.Context, ec *ethclient.Client) ([]types.Log, error) {
+		return ec.FilterLogs(ctx, query)
+	})
+	if err != nil {
+		if IsRangeLimitError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get logs after %d attempts: %w", c.cfg.RetryAttempts, err)
+	}
+	return logs, nil
+}
@@ -0,0 +1,137 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog/log"
+)
+
+// Calls aggregated into a single eth_call
+const multicallChunkSize = 500
+
+// multicall3ABI covers aggregate3((address,bool,bytes)[]) returns ((bool,bytes)[])
+const multicall3ABI = `[{"inputs":[{"components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}],"name":"calls","type":"tuple[]"}],"name":"aggregate3","outputs":[{"components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}],"name":"returnData","type":"tuple[]"}],"stateMutability":"payable","type":"function"}]`
+
+var multicall3 = mustParseABI(multicall3ABI)
+
+// errCallFailed is the error of a call that reverted inside a multicall
+var errCallFailed = errors.New("call reverted")
+
+// Call is one contract call in a multicall
+type Call struct {
+	Target common.Address
+	Data   []byte
+}
+
+type multicallCall struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type multicallResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// Multicall executes calls through Multicall3's aggregate3, in chunks that
+// are themselves sent as one JSON-RPC batch. Results are in the order of
+// calls; a failing call only fails its own result. Without a configured
+// Multicall3 address, or if the aggregate call fails, the calls are sent as
+// individual eth_calls in a batch instead.
+func (c *Client) Multicall(ctx context.Context, calls []Call, blockNumber *big.Int) ([]CallResult, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+	if c.cfg.MulticallAddress == "" {
+		return c.callEach(ctx, calls, blockNumber)
+	}
+	target := common.HexToAddress(c.cfg.MulticallAddress)
+
+	var chunks [][]Call
+	msgs := make([]ethereum.CallMsg, 0, len(calls)/multicallChunkSize+1)
+	for start := 0; start < len(calls); start += multicallChunkSize {
+		chunk := calls[start:min(start+multicallChunkSize, len(calls))]
+
+		packed := make([]multicallCall, len(chunk))
+		for idx, call := range chunk {
+			packed[idx] = multicallCall{Target: call.Target, AllowFailure: true, CallData: call.Data}
+		}
+		data, err := multicall3.Pack("aggregate3", packed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to pack multicall: %w", err)
+		}
+
+		chunks = append(chunks, chunk)
+		msgs = append(msgs, ethereum.CallMsg{To: &target, Data: data})
+	}
+
+	responses, err := c.CallContracts(ctx, msgs, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]CallResult, 0, len(calls))
+	for idx, resp := range responses {
+		chunkResults, err := unpackMulticall(resp, len(chunks[idx]))
+		if err != nil {
+			log.Debug().Err(err).Int("calls", len(chunks[idx])).Msg("Multicall failed, falling back to individual calls")
+			if chunkResults, err = c.callEach(ctx, chunks[idx], blockNumber); err != nil {
+				return nil, err
+			}
+		}
+		results = append(results, chunkResults...)
+	}
+
+	return results, nil
+}
+
+// unpackMulticall decodes the result of one aggregate3 call
+func unpackMulticall(resp CallResult, n int) ([]CallResult, error) {
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+
+	out, err := multicall3.Unpack("aggregate3", resp.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack multicall: %w", err)
+	}
+	decoded := *abi.ConvertType(out[0], new([]multicallResult)).(*[]multicallResult)
+	if len(decoded) != n {
+		return nil, fmt.Errorf("multicall returned %d results for %d calls", len(decoded), n)
+	}
+
+	results := make([]CallResult, n)
+	for idx, r := range decoded {
+		if r.Success {
+			results[idx] = CallResult{Data: r.ReturnData}
+		} else {
+			results[idx] = CallResult{Err: errCallFailed}
+		}
+	}
+	return results, nil
+}
+
+// callEach sends calls as individual eth_calls in a JSON-RPC batch
+func (c *Client) callEach(ctx context.Context, calls []Call, blockNumber *big.Int) ([]CallResult, error) {
+	msgs := make([]ethereum.CallMsg, len(calls))
+	for idx := range calls {
+		msgs[idx] = ethereum.CallMsg{To: &calls[idx].Target, Data: calls[idx].Data}
+	}
+	return c.CallContracts(ctx, msgs, blockNumber)
+}
+
+func mustParseABI(raw string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(raw))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
@@ -0,0 +1,28 @@
+package eth
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Reader is the chain access the decoders and detectors need. *Client
+// implements it; tests can use a Client whose transport replays recorded
+// responses, or a fake of their own.
+type Reader interface {
+	ChainID() *big.Int
+	BlockNumber(ctx context.Context) (uint64, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	GetLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error)
+	TransactionAndReceipt(ctx context.Context, txHash common.Hash) (*types.Transaction, *types.Receipt, error)
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	CallContracts(ctx context.Context, msgs []ethereum.CallMsg, blockNumber *big.Int) ([]CallResult, error)
+	Multicall(ctx context.Context, calls []Call, blockNumber *big.Int) ([]CallResult, error)
+}
+
+var _ Reader = (*Client)(nil)
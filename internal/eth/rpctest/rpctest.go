@@ -0,0 +1,331 @@
+// Package rpctest records JSON-RPC traffic to fixture files and replays it,
+// so code built on eth.Client can be tested against real chain data without
+// a node.
+package rpctest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// RecordEnv enables recording in Transport when set. The recording is made
+// against the node at RPCURLEnv.
+const (
+	RecordEnv = "MEV_RECORD_FIXTURES"
+	RPCURLEnv = "MEV_RPC_URL"
+)
+
+// Entry is one recorded request/response pair. Requests are matched on
+// method and params; the JSON-RPC id is ignored.
+type Entry struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error object
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+type request struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type response struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// key identifies a request independent of its id and formatting
+func key(method string, params json.RawMessage) string {
+	if len(params) == 0 {
+		params = json.RawMessage("null")
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, params); err != nil {
+		return method + string(params)
+	}
+	return method + buf.String()
+}
+
+// Recorder is an http.RoundTripper that forwards requests to a node and
+// keeps every request/response pair it sees
+type Recorder struct {
+	base    http.RoundTripper
+	entries map[string]Entry
+	mu      sync.Mutex
+}
+
+// NewRecorder creates a Recorder that forwards requests through base, or
+// http.DefaultTransport if base is nil
+func NewRecorder(base http.RoundTripper) *Recorder {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Recorder{
+		base:    base,
+		entries: make(map[string]Entry),
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	req.ContentLength = int64(len(reqBody))
+
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := readBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	if resp.StatusCode == http.StatusOK {
+		r.record(reqBody, respBody)
+	}
+	return resp, nil
+}
+
+// record pairs the requests and responses of one exchange by id
+func (r *Recorder) record(reqBody, respBody []byte) {
+	reqs, _, err := decodeRequests(reqBody)
+	if err != nil {
+		return
+	}
+	var resps []response
+	if isBatch(respBody) {
+		err = json.Unmarshal(respBody, &resps)
+	} else {
+		var resp response
+		err = json.Unmarshal(respBody, &resp)
+		resps = []response{resp}
+	}
+	if err != nil {
+		return
+	}
+
+	byID := make(map[string]response, len(resps))
+	for _, resp := range resps {
+		byID[string(resp.ID)] = resp
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, req := range reqs {
+		resp, ok := byID[string(req.ID)]
+		if !ok {
+			continue
+		}
+		r.entries[key(req.Method, req.Params)] = Entry{
+			Method: req.Method,
+			Params: req.Params,
+			Result: resp.Result,
+			Error:  resp.Error,
+		}
+	}
+}
+
+// Entries returns the recorded pairs, sorted by method and params
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]string, 0, len(r.entries))
+	for k := range r.entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	entries := make([]Entry, 0, len(keys))
+	for _, k := range keys {
+		entries = append(entries, r.entries[k])
+	}
+	return entries
+}
+
+// Save writes the recorded pairs to a fixture file
+func (r *Recorder) Save(path string) error {
+	data, err := json.MarshalIndent(r.Entries(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return nil
+}
+
+// Replayer is an http.RoundTripper that answers JSON-RPC requests from a
+// fixture file. Requests that weren't recorded get a JSON-RPC error.
+type Replayer struct {
+	entries map[string]Entry
+}
+
+// NewReplayer creates a Replayer serving entries
+func NewReplayer(entries []Entry) *Replayer {
+	r := &Replayer{entries: make(map[string]Entry, len(entries))}
+	for _, e := range entries {
+		r.entries[key(e.Method, e.Params)] = e
+	}
+	return r
+}
+
+// LoadReplayer creates a Replayer serving a fixture file written by
+// Recorder.Save
+func LoadReplayer(path string) (*Replayer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode fixture %s: %w", path, err)
+	}
+	return NewReplayer(entries), nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	reqs, batch, err := decodeRequests(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode JSON-RPC request: %w", err)
+	}
+
+	resps := make([]response, 0, len(reqs))
+	for _, rq := range reqs {
+		resp := response{Version: "2.0", ID: rq.ID}
+		if e, ok := r.entries[key(rq.Method, rq.Params)]; ok {
+			resp.Result, resp.Error = e.Result, e.Error
+			if resp.Result == nil && resp.Error == nil {
+				resp.Result = json.RawMessage("null")
+			}
+		} else {
+			resp.Error = &Error{
+				Code:    -32000,
+				Message: fmt.Sprintf("no recorded response for %s %s", rq.Method, rq.Params),
+			}
+		}
+		resps = append(resps, resp)
+	}
+
+	var out []byte
+	if batch {
+		out, err = json.Marshal(resps)
+	} else {
+		out, err = json.Marshal(resps[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(out)),
+		ContentLength: int64(len(out)),
+		Request:       req,
+	}, nil
+}
+
+// Transport returns the transport a test should give eth.NewClient, along
+// with the RPC URL to dial. By default it replays the fixture at path. With
+// MEV_RECORD_FIXTURES set it records against the node at MEV_RPC_URL
+// instead and rewrites the fixture when the test finishes.
+func Transport(t testing.TB, path string) (http.RoundTripper, string) {
+	t.Helper()
+
+	if os.Getenv(RecordEnv) == "" {
+		replayer, err := LoadReplayer(path)
+		if err != nil {
+			t.Fatalf("%v (set %s and %s to record it)", err, RecordEnv, RPCURLEnv)
+		}
+		return replayer, "http://replay.invalid"
+	}
+
+	url := os.Getenv(RPCURLEnv)
+	if url == "" {
+		t.Fatalf("%s is set but %s is empty", RecordEnv, RPCURLEnv)
+	}
+	return Record(t, path), url
+}
+
+// Record returns a transport that records the test's requests and rewrites
+// the fixture at path when the test finishes, unless it failed
+func Record(t testing.TB, path string) http.RoundTripper {
+	t.Helper()
+
+	recorder := NewRecorder(nil)
+	t.Cleanup(func() {
+		if t.Failed() {
+			return
+		}
+		if err := recorder.Save(path); err != nil {
+			t.Error(err)
+		}
+	})
+	return recorder
+}
+
+// decodeRequests parses a single or batch JSON-RPC request body
+func decodeRequests(body []byte) ([]request, bool, error) {
+	if isBatch(body) {
+		var reqs []request
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			return nil, true, err
+		}
+		return reqs, true, nil
+	}
+
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, false, err
+	}
+	return []request{req}, false, nil
+}
+
+// isBatch reports whether a JSON-RPC body is an array
+func isBatch(body []byte) bool {
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+func readBody(body io.ReadCloser) ([]byte, error) {
+	if body == nil {
+		return nil, nil
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
@@ -0,0 +1,102 @@
+package rpctest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type testService struct{}
+
+func (testService) Echo(s string) string { return s }
+
+func (testService) Fail() error { return testError{} }
+
+type testError struct{}
+
+func (testError) Error() string  { return "execution reverted" }
+func (testError) ErrorCode() int { return 3 }
+
+func dial(t *testing.T, url string, transport http.RoundTripper) *rpc.Client {
+	t.Helper()
+	client, err := rpc.DialOptions(context.Background(), url, rpc.WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestRecordReplay(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("test", testService{}); err != nil {
+		t.Fatal(err)
+	}
+	node := httptest.NewServer(server)
+	defer node.Close()
+
+	// Record a single call, a batch and an error against the live server
+	recorder := NewRecorder(nil)
+	live := dial(t, node.URL, recorder)
+
+	var single string
+	if err := live.Call(&single, "test_echo", "a"); err != nil {
+		t.Fatalf("failed to call test_echo: %v", err)
+	}
+	var b, c string
+	batch := []rpc.BatchElem{
+		{Method: "test_echo", Args: []interface{}{"b"}, Result: &b},
+		{Method: "test_echo", Args: []interface{}{"c"}, Result: &c},
+		{Method: "test_fail", Result: new(string)},
+	}
+	if err := live.BatchCall(batch); err != nil {
+		t.Fatalf("failed to send batch: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	if err := recorder.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	node.Close()
+
+	// Replay the same calls, in a different grouping, without the server
+	replayer, err := LoadReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := dial(t, "http://replay.invalid", replayer)
+
+	for _, want := range []string{"a", "b", "c"} {
+		var got string
+		if err := replay.Call(&got, "test_echo", want); err != nil {
+			t.Fatalf("failed to replay test_echo(%q): %v", want, err)
+		}
+		if got != want {
+			t.Errorf("test_echo(%q) = %q", want, got)
+		}
+	}
+
+	if err := replay.Call(new(string), "test_fail"); err == nil || !strings.Contains(err.Error(), "execution reverted") {
+		t.Errorf("test_fail error = %v, want the recorded error", err)
+	}
+
+	var missing string
+	batch = []rpc.BatchElem{
+		{Method: "test_echo", Args: []interface{}{"a"}, Result: &single},
+		{Method: "test_echo", Args: []interface{}{"d"}, Result: &missing},
+	}
+	if err := replay.BatchCall(batch); err != nil {
+		t.Fatalf("failed to replay batch: %v", err)
+	}
+	if batch[0].Error != nil || single != "a" {
+		t.Errorf("recorded batch element = %q, %v", single, batch[0].Error)
+	}
+	if batch[1].Error == nil || !strings.Contains(batch[1].Error.Error(), "no recorded response") {
+		t.Errorf("unrecorded batch element error = %v", batch[1].Error)
+	}
+}
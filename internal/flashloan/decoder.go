@@ -0,0 +1,262 @@
+package flashloan
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+
+	"github.com/devlongs/mev-inspector/internal/eth"
+	"github.com/devlongs/mev-inspector/internal/metrics"
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+// Aave V2 FlashLoan event signature
+// event FlashLoan(address indexed target, address indexed initiator, address indexed asset, uint256 amount, uint256 premium, uint16 referralCode)
+var AaveV2FlashLoanSignature = common.HexToHash("0x631042c832b07452973831137f2d73e395028b44b250dedc5abb0ee766e168ac")
+
+// Aave V3 FlashLoan event signature
+// event FlashLoan(address indexed target, address initiator, address indexed asset, uint256 amount, uint8 interestRateMode, uint256 premium, uint16 indexed referralCode)
+var AaveV3FlashLoanSignature = common.HexToHash("0xefefaba5e921573100900a3ad9cf29f222d995fb3b6045797eaea7521bd8d6f0")
+
+// Balancer V2 Vault FlashLoan event signature
+// event FlashLoan(address indexed recipient, address indexed token, uint256 amount, uint256 feeAmount)
+var BalancerFlashLoanSignature = common.HexToHash("0x0d7d75e01ab95780d3cd1c8ec0dd6c2ce19e3a20427eec8bf53283b6fb8e95f0")
+
+// Uniswap V3 pool Flash event signature
+// event Flash(address indexed sender, address indexed recipient, uint256 amount0, uint256 amount1, uint256 paid0, uint256 paid1)
+var UniswapV3FlashSignature = common.HexToHash("0xbdbdb71d7860376ba52b25a5028beea23581364a40522f6bcfb86bb1f2dca633")
+
+// Decoder decodes flash loan events from transaction receipts
+type Decoder struct {
+	client    eth.Reader
+	poolCache map[common.Address]*PoolInfo
+	mu        sync.RWMutex
+}
+
+// PoolInfo holds cached tokens of a Uniswap V3 pool used for flash loans
+type PoolInfo struct {
+	Token0 common.Address
+	Token1 common.Address
+}
+
+// NewDecoder creates a new flash loan decoder
+func NewDecoder(client eth.Reader) *Decoder {
+	return &Decoder{
+		client:    client,
+		poolCache: make(map[common.Address]*PoolInfo),
+	}
+}
+
+// DecodeFlashLoans returns every flash loan found in a transaction's logs
+func (d *Decoder) DecodeFlashLoans(ctx context.Context, logs []*ethtypes.Log) []types.FlashLoan {
+	var loans []types.FlashLoan
+
+	for _, l := range logs {
+		if l == nil || len(l.Topics) == 0 {
+			continue
+		}
+
+		var decoded []types.FlashLoan
+		var err error
+
+		switch l.Topics[0] {
+		case AaveV2FlashLoanSignature:
+			decoded, err = decodeAaveV2(l)
+		case AaveV3FlashLoanSignature:
+			decoded, err = decodeAaveV3(l)
+		case BalancerFlashLoanSignature:
+			decoded, err = decodeBalancer(l)
+		case UniswapV3FlashSignature:
+			decoded, err = d.decodeUniswapV3(ctx, l)
+		default:
+			continue
+		}
+
+		if err != nil {
+			log.Debug().Err(err).Str("txHash", l.TxHash.Hex()).Msg("Failed to decode flash loan")
+			continue
+		}
+		loans = append(loans, decoded...)
+	}
+
+	return loans
+}
+
+// FeesIn sums the fees of all flash loans denominated in token
+func FeesIn(loans []types.FlashLoan, token common.Address) *big.Int {
+	total := big.NewInt(0)
+	for _, loan := range loans {
+		if loan.Token == token && loan.Fee != nil {
+			total.Add(total, loan.Fee)
+		}
+	}
+	return total
+}
+
+// HasFeesOutside reports whether any flash loan charged a fee in a token
+// other than token
+func HasFeesOutside(loans []types.FlashLoan, token common.Address) bool {
+	for _, loan := range loans {
+		if loan.Token != token && loan.Fee != nil && loan.Fee.Sign() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeAaveV2 decodes an Aave V2 FlashLoan event
+func decodeAaveV2(l *ethtypes.Log) ([]types.FlashLoan, error) {
+	if len(l.Topics) < 4 || len(l.Data) < 64 {
+		return nil, fmt.Errorf("invalid Aave V2 FlashLoan log")
+	}
+
+	return []types.FlashLoan{{
+		Protocol: "aave_v2",
+		Lender:   l.Address,
+		Borrower: common.HexToAddress(l.Topics[1].Hex()),
+		Token:    common.HexToAddress(l.Topics[3].Hex()),
+		Amount:   new(big.Int).SetBytes(l.Data[0:32]),
+		Fee:      new(big.Int).SetBytes(l.Data[32:64]),
+	}}, nil
+}
+
+// decodeAaveV3 decodes an Aave V3 FlashLoan event. A non-zero
+// interestRateMode means the amount wasn't repaid but opened as debt, so
+// it isn't a flash loan and carries no premium.
+func decodeAaveV3(l *ethtypes.Log) ([]types.FlashLoan, error) {
+	// initiator, amount, interestRateMode, premium
+	if len(l.Topics) < 3 || len(l.Data) < 128 {
+		return nil, fmt.Errorf("invalid Aave V3 FlashLoan log")
+	}
+
+	if new(big.Int).SetBytes(l.Data[64:96]).Sign() != 0 {
+		return nil, nil
+	}
+
+	return []types.FlashLoan{{
+		Protocol: "aave_v3",
+		Lender:   l.Address,
+		Borrower: common.HexToAddress(l.Topics[1].Hex()),
+		Token:    common.HexToAddress(l.Topics[2].Hex()),
+		Amount:   new(big.Int).SetBytes(l.Data[32:64]),
+		Fee:      new(big.Int).SetBytes(l.Data[96:128]),
+	}}, nil
+}
+
+// decodeBalancer decodes a Balancer V2 Vault FlashLoan event
+func decodeBalancer(l *ethtypes.Log) ([]types.FlashLoan, error) {
+	if len(l.Topics) < 3 || len(l.Data) < 64 {
+		return nil, fmt.Errorf("invalid Balancer FlashLoan log")
+	}
+
+	return []types.FlashLoan{{
+		Protocol: "balancer_v2",
+		Lender:   l.Address,
+		Borrower: common.HexToAddress(l.Topics[1].Hex()),
+		Token:    common.HexToAddress(l.Topics[2].Hex()),
+		Amount:   new(big.Int).SetBytes(l.Data[0:32]),
+		Fee:      new(big.Int).SetBytes(l.Data[32:64]),
+	}}, nil
+}
+
+// decodeUniswapV3 decodes a Uniswap V3 pool Flash event, which can borrow
+// both pool tokens at once. paid0/paid1 are the fees paid on top of the loan.
+func (d *Decoder) decodeUniswapV3(ctx context.Context, l *ethtypes.Log) ([]types.FlashLoan, error) {
+	if len(l.Topics) < 3 || len(l.Data) < 128 {
+		return nil, fmt.Errorf("invalid Uniswap V3 Flash log")
+	}
+
+	poolInfo, err := d.getPoolInfo(ctx, l.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool info: %w", err)
+	}
+
+	borrower := common.HexToAddress(l.Topics[2].Hex())
+	amounts := []*big.Int{
+		new(big.Int).SetBytes(l.Data[0:32]),
+		new(big.Int).SetBytes(l.Data[32:64]),
+	}
+	fees := []*big.Int{
+		new(big.Int).SetBytes(l.Data[64:96]),
+		new(big.Int).SetBytes(l.Data[96:128]),
+	}
+	tokens := []common.Address{poolInfo.Token0, poolInfo.Token1}
+
+	var loans []types.FlashLoan
+	for idx := range tokens {
+		if amounts[idx].Sign() == 0 {
+			continue
+		}
+		loans = append(loans, types.FlashLoan{
+			Protocol: "uniswap_v3",
+			Lender:   l.Address,
+			Borrower: borrower,
+			Token:    tokens[idx],
+			Amount:   amounts[idx],
+			Fee:      fees[idx],
+		})
+	}
+
+	return loans, nil
+}
+
+// getPoolInfo fetches and caches the tokens of a Uniswap V3 pool
+func (d *Decoder) getPoolInfo(ctx context.Context, poolAddress common.Address) (*PoolInfo, error) {
+	// Check cache first
+	d.mu.RLock()
+	info, ok := d.poolCache[poolAddress]
+	d.mu.RUnlock()
+	metrics.PoolCacheLookup("flashloan", ok)
+	if ok {
+		return info, nil
+	}
+
+	// token0() selector: 0x0dfe1681
+	token0, err := d.callAddress(ctx, poolAddress, "0dfe1681")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token0: %w", err)
+	}
+
+	// token1() selector: 0xd21220a7
+	token1, err := d.callAddress(ctx, poolAddress, "d21220a7")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token1: %w", err)
+	}
+
+	info = &PoolInfo{
+		Token0: token0,
+		Token1: token1,
+	}
+
+	// Cache the result
+	d.mu.Lock()
+	d.poolCache[poolAddress] = info
+	d.mu.Unlock()
+
+	return info, nil
+}
+
+// callAddress calls an address-returning getter with no arguments
+func (d *Decoder) callAddress(ctx context.Context, contract common.Address, selector string) (common.Address, error) {
+	msg := ethereum.CallMsg{
+		To:   &contract,
+		Data: common.Hex2Bytes(selector),
+	}
+
+	result, err := d.client.CallContract(ctx, msg, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	if len(result) < 32 {
+		return common.Address{}, fmt.Errorf("invalid address response")
+	}
+
+	return common.BytesToAddress(result[12:32]), nil
+}
@@ -0,0 +1,529 @@
+package liquidation
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+
+	"github.com/devlongs/mev-inspector/internal/arbitrage"
+	"github.com/devlongs/mev-inspector/internal/eth"
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+// Aave V2/V3 LiquidationCall event signature
+// event LiquidationCall(address indexed collateralAsset, address indexed debtAsset, address indexed user, uint256 debtToCover, uint256 liquidatedCollateralAmount, address liquidator, bool receiveAToken)
+var LiquidationCallSignature = common.HexToHash("0xe413a321e8681d831f4dbccbca790d2952b56f977908e45be37335533e005286")
+
+// Compound V2 LiquidateBorrow event signature (emitted by the borrowed cToken)
+// event LiquidateBorrow(address liquidator, address borrower, uint repayAmount, address cTokenCollateral, uint seizeTokens)
+var LiquidateBorrowSignature = common.HexToHash("0x298637f684da70674f26509b10f07ec2fbc77a335ab1e7d6215a4b2484d8bb52")
+
+// Compound V3 (Comet) AbsorbCollateral event signature
+// event AbsorbCollateral(address indexed absorber, address indexed borrower, address indexed asset, uint collateralAbsorbed, uint usdValue)
+var AbsorbCollateralSignature = common.HexToHash("0x9850ab1af75177e4a9201c65a2cf7976d5d28e40ef63494b44366f86b2f9412e")
+
+// Compound V2 Comptroller MarketListed event signature
+// event MarketListed(address cToken)
+var MarketListedSignature = common.HexToHash("0xcf583bb0c569eb967f806b11601c4cb93c10310485c67add5f8362c2f212321f")
+
+// Aave lending pool addresses on mainnet
+var (
+	AaveV2LendingPool = common.HexToAddress("0x7d2768dE32b0b80b7a3454c06BdAc94A69DDc7A9")
+	AaveV3Pool        = common.HexToAddress("0x87870Bca3F3fD6335C3F4ce8392D69350B4fA4E2")
+)
+
+// Compound V2 Comptroller (Unitroller proxy) on mainnet, which lists the
+// cToken markets that emit LiquidateBorrow
+var CompoundComptroller = common.HexToAddress("0x3d9819210A31b4961b30EF54bE2aeD79B9c9Cd3B")
+
+// Compound V3 Comet markets on mainnet
+var CometMarkets = []common.Address{
+	common.HexToAddress("0xc3d688B66703497DAA19211EEdff47f25384cdc3"), // cUSDCv3
+	common.HexToAddress("0xA17581A9E3356d9A858b789D68B4d866e593aE94"), // cWETHv3
+	common.HexToAddress("0x3Afdc9BCA9213A35503b2B5e8a0f32DD09Cc7F4a"), // cUSDTv3
+}
+
+// Compound V2 cETH has no underlying() and holds native ETH
+var CEther = common.HexToAddress("0x4Ddc2D193948926D02f9B1fE9e1daa0718270ED5")
+
+// WETH address on mainnet, reported as the asset of cETH markets
+var WETH = common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+
+// Detector detects liquidations on Aave and Compound
+type Detector struct {
+	client          eth.Reader
+	underlyingCache map[common.Address]common.Address
+	compoundMarkets []common.Address // cTokens listed by the Comptroller, loaded once and extended by MarketListed logs
+	mu              sync.RWMutex
+}
+
+// NewDetector creates a new liquidation detector
+func NewDetector(client eth.Reader) *Detector {
+	return &Detector{
+		client:          client,
+		underlyingCache: make(map[common.Address]common.Address),
+	}
+}
+
+// GetLiquidationLogs fetches all supported liquidation logs in a block range.
+// Only the known Aave pools and Compound markets are queried, so forks that
+// reuse the same events aren't reported under these protocols. Markets the
+// Comptroller lists in the range are added to the known markets and their
+// liquidations fetched as well.
+func (d *Detector) GetLiquidationLogs(ctx context.Context, fromBlock, toBlock uint64) ([]ethtypes.Log, error) {
+	markets, err := d.getCompoundMarkets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Compound markets: %w", err)
+	}
+
+	addresses := []common.Address{AaveV2LendingPool, AaveV3Pool, CompoundComptroller}
+	addresses = append(addresses, markets...)
+	addresses = append(addresses, CometMarkets...)
+
+	query := ethereum.FilterQuery{
+		FromBlock: big.NewInt(int64(fromBlock)),
+		ToBlock:   big.NewInt(int64(toBlock)),
+		Addresses: addresses,
+		Topics: [][]common.Hash{
+			{LiquidationCallSignature, LiquidateBorrowSignature, AbsorbCollateralSignature, MarketListedSignature},
+		},
+	}
+
+	logs, err := d.client.GetLogs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var liquidations []ethtypes.Log
+	var listed []common.Address
+	for _, l := range logs {
+		if l.Topics[0] != MarketListedSignature {
+			liquidations = append(liquidations, l)
+			continue
+		}
+		if l.Address == CompoundComptroller && len(l.Data) >= 32 {
+			listed = append(listed, common.BytesToAddress(l.Data[12:32]))
+		}
+	}
+
+	listed = d.addCompoundMarkets(listed)
+	if len(listed) == 0 {
+		return liquidations, nil
+	}
+
+	// The new markets weren't part of the query above
+	query.Addresses = listed
+	query.Topics = [][]common.Hash{{LiquidateBorrowSignature}}
+	more, err := d.client.GetLogs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	liquidations = append(liquidations, more...)
+	sort.Slice(liquidations, func(i, j int) bool {
+		if liquidations[i].BlockNumber != liquidations[j].BlockNumber {
+			return liquidations[i].BlockNumber < liquidations[j].BlockNumber
+		}
+		return liquidations[i].Index < liquidations[j].Index
+	})
+
+	return liquidations, nil
+}
+
+// DecodeLiquidationLog decodes a single liquidation log into a Liquidation struct
+func (d *Detector) DecodeLiquidationLog(ctx context.Context, log ethtypes.Log) (*types.Liquidation, error) {
+	if len(log.Topics) == 0 {
+		return nil, fmt.Errorf("invalid liquidation log: no topics")
+	}
+
+	switch log.Topics[0] {
+	case LiquidationCallSignature:
+		return d.decodeAaveLiquidation(log)
+	case LiquidateBorrowSignature:
+		return d.decodeCompoundV2Liquidation(ctx, log)
+	case AbsorbCollateralSignature:
+		return d.decodeCompoundV3Absorb(ctx, log)
+	}
+
+	return nil, fmt.Errorf("not a liquidation event")
+}
+
+// AttachSwaps joins the swaps of the liquidation transaction and computes
+// the liquidator's profit when the collateral was swapped back to the debt
+// asset. Absorbed collateral goes to the protocol, so absorbs have no profit.
+func (d *Detector) AttachSwaps(liq *types.Liquidation, swaps []types.Swap) {
+	liq.Swaps = swaps
+	liq.Profit = nil
+	liq.ProfitToken = common.Address{}
+
+	if liq.Absorbed {
+		return
+	}
+
+	// Collateral and debt in the same token need no conversion
+	if liq.CollateralAsset == liq.DebtAsset {
+		liq.Profit = new(big.Int).Sub(liq.CollateralAmount, liq.DebtRepaid)
+		liq.ProfitToken = liq.DebtAsset
+		return
+	}
+
+	// Net token flow of the liquidator's swaps
+	net := make(map[common.Address]*big.Int)
+	for _, swap := range swaps {
+		flow, ok := arbitrage.FlowOf(&swap)
+		if !ok {
+			continue
+		}
+		if net[flow.TokenIn] == nil {
+			net[flow.TokenIn] = big.NewInt(0)
+		}
+		if net[flow.TokenOut] == nil {
+			net[flow.TokenOut] = big.NewInt(0)
+		}
+		net[flow.TokenIn].Sub(net[flow.TokenIn], flow.AmountIn)
+		net[flow.TokenOut].Add(net[flow.TokenOut], flow.AmountOut)
+	}
+
+	collateralFlow, debtFlow := net[liq.CollateralAsset], net[liq.DebtAsset]
+	if collateralFlow == nil || debtFlow == nil || collateralFlow.Sign() >= 0 || debtFlow.Sign() <= 0 {
+		return
+	}
+
+	liq.Profit = new(big.Int).Sub(debtFlow, liq.DebtRepaid)
+	liq.ProfitToken = liq.DebtAsset
+}
+
+// decodeAaveLiquidation decodes an Aave V2/V3 LiquidationCall event
+func (d *Detector) decodeAaveLiquidation(log ethtypes.Log) (*types.Liquidation, error) {
+	if len(log.Topics) < 4 {
+		return nil, fmt.Errorf("invalid LiquidationCall log: expected 4 topics, got %d", len(log.Topics))
+	}
+
+	// debtToCover, liquidatedCollateralAmount, liquidator, receiveAToken
+	if len(log.Data) < 128 {
+		return nil, fmt.Errorf("invalid LiquidationCall data length: expected 128 bytes, got %d", len(log.Data))
+	}
+
+	var protocol string
+	switch log.Address {
+	case AaveV2LendingPool:
+		protocol = "aave_v2"
+	case AaveV3Pool:
+		protocol = "aave_v3"
+	default:
+		return nil, fmt.Errorf("LiquidationCall not emitted by a known Aave pool: %s", log.Address.Hex())
+	}
+
+	return &types.Liquidation{
+		TxHash:           log.TxHash,
+		BlockNumber:      log.BlockNumber,
+		LogIndex:         log.Index,
+		Protocol:         protocol,
+		Liquidator:       common.BytesToAddress(log.Data[76:96]),
+		Borrower:         common.HexToAddress(log.Topics[3].Hex()),
+		CollateralAsset:  common.HexToAddress(log.Topics[1].Hex()),
+		CollateralAmount: new(big.Int).SetBytes(log.Data[32:64]),
+		DebtAsset:        common.HexToAddress(log.Topics[2].Hex()),
+		DebtRepaid:       new(big.Int).SetBytes(log.Data[0:32]),
+	}, nil
+}
+
+// decodeCompoundV2Liquidation decodes a Compound V2 LiquidateBorrow event.
+// seizeTokens includes the protocol's share of the seized collateral, which
+// goes to reserves; the liquidator's part is converted to underlying using
+// the collateral's exchange rate.
+func (d *Detector) decodeCompoundV2Liquidation(ctx context.Context, log ethtypes.Log) (*types.Liquidation, error) {
+	// liquidator, borrower, repayAmount, cTokenCollateral, seizeTokens
+	if len(log.Data) < 160 {
+		return nil, fmt.Errorf("invalid LiquidateBorrow data length: expected 160 bytes, got %d", len(log.Data))
+	}
+
+	cTokenCollateral := common.BytesToAddress(log.Data[108:128])
+	seizeTokens := new(big.Int).SetBytes(log.Data[128:160])
+
+	debtAsset, err := d.getUnderlying(ctx, log.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get debt underlying: %w", err)
+	}
+
+	collateralAsset, err := d.getUnderlying(ctx, cTokenCollateral)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collateral underlying: %w", err)
+	}
+
+	exchangeRate, err := d.callExchangeRateStored(ctx, cTokenCollateral, log.BlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange rate: %w", err)
+	}
+
+	seizeShare, err := d.callProtocolSeizeShare(ctx, cTokenCollateral, log.BlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get protocol seize share: %w", err)
+	}
+
+	// liquidator cTokens = seizeTokens - seizeTokens * protocolSeizeShare / 1e18
+	protocolTokens := new(big.Int).Mul(seizeTokens, seizeShare)
+	protocolTokens.Quo(protocolTokens, big.NewInt(1e18))
+	liquidatorTokens := new(big.Int).Sub(seizeTokens, protocolTokens)
+
+	// underlying = cTokens * exchangeRate / 1e18
+	collateralAmount := new(big.Int).Mul(liquidatorTokens, exchangeRate)
+	collateralAmount.Quo(collateralAmount, big.NewInt(1e18))
+
+	return &types.Liquidation{
+		TxHash:           log.TxHash,
+		BlockNumber:      log.BlockNumber,
+		LogIndex:         log.Index,
+		Protocol:         "compound_v2",
+		Liquidator:       common.BytesToAddress(log.Data[12:32]),
+		Borrower:         common.BytesToAddress(log.Data[44:64]),
+		CollateralAsset:  collateralAsset,
+		CollateralAmount: collateralAmount,
+		DebtAsset:        debtAsset,
+		DebtRepaid:       new(big.Int).SetBytes(log.Data[64:96]),
+	}, nil
+}
+
+// decodeCompoundV3Absorb decodes a Compound V3 AbsorbCollateral event. In
+// Comet the protocol itself absorbs the collateral and the base-token debt:
+// the absorber repays nothing and receives none of the collateral, so the
+// result is marked Absorbed with a zero DebtRepaid. Absorbers profit later,
+// if at all, by buying the collateral from the protocol at a discount.
+func (d *Detector) decodeCompoundV3Absorb(ctx context.Context, log ethtypes.Log) (*types.Liquidation, error) {
+	if len(log.Topics) < 4 {
+		return nil, fmt.Errorf("invalid AbsorbCollateral log: expected 4 topics, got %d", len(log.Topics))
+	}
+
+	// collateralAbsorbed, usdValue
+	if len(log.Data) < 64 {
+		return nil, fmt.Errorf("invalid AbsorbCollateral data length: expected 64 bytes, got %d", len(log.Data))
+	}
+
+	baseToken, err := d.getBaseToken(ctx, log.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get base token: %w", err)
+	}
+
+	return &types.Liquidation{
+		TxHash:           log.TxHash,
+		BlockNumber:      log.BlockNumber,
+		LogIndex:         log.Index,
+		Protocol:         "compound_v3",
+		Absorbed:         true,
+		Liquidator:       common.HexToAddress(log.Topics[1].Hex()),
+		Borrower:         common.HexToAddress(log.Topics[2].Hex()),
+		CollateralAsset:  common.HexToAddress(log.Topics[3].Hex()),
+		CollateralAmount: new(big.Int).SetBytes(log.Data[0:32]),
+		DebtAsset:        baseToken,
+		DebtRepaid:       big.NewInt(0),
+	}, nil
+}
+
+// getUnderlying fetches and caches the underlying asset of a cToken
+func (d *Detector) getUnderlying(ctx context.Context, cToken common.Address) (common.Address, error) {
+	// Check cache first
+	d.mu.RLock()
+	underlying, ok := d.underlyingCache[cToken]
+	d.mu.RUnlock()
+	if ok {
+		return underlying, nil
+	}
+
+	if cToken == CEther {
+		underlying = WETH
+	} else {
+		// underlying() selector: 0x6f307dc3
+		result, err := d.callAddress(ctx, cToken, "6f307dc3")
+		if err != nil {
+			return common.Address{}, err
+		}
+		underlying = result
+	}
+
+	// Cache the result
+	d.mu.Lock()
+	d.underlyingCache[cToken] = underlying
+	d.mu.Unlock()
+
+	log.Debug().
+		Str("cToken", cToken.Hex()).
+		Str("underlying", underlying.Hex()).
+		Msg("Cached cToken underlying")
+
+	return underlying, nil
+}
+
+// getBaseToken fetches and caches the base token of a Comet market
+func (d *Detector) getBaseToken(ctx context.Context, comet common.Address) (common.Address, error) {
+	// Check cache first
+	d.mu.RLock()
+	baseToken, ok := d.underlyingCache[comet]
+	d.mu.RUnlock()
+	if ok {
+		return baseToken, nil
+	}
+
+	// baseToken() selector: 0xc55dae63
+	baseToken, err := d.callAddress(ctx, comet, "c55dae63")
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	// Cache the result
+	d.mu.Lock()
+	d.underlyingCache[comet] = baseToken
+	d.mu.Unlock()
+
+	return baseToken, nil
+}
+
+// callAddress calls an address-returning getter with no arguments
+func (d *Detector) callAddress(ctx context.Context, contract common.Address, selector string) (common.Address, error) {
+	msg := ethereum.CallMsg{
+		To:   &contract,
+		Data: common.Hex2Bytes(selector),
+	}
+
+	result, err := d.client.CallContract(ctx, msg, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	if len(result) < 32 {
+		return common.Address{}, fmt.Errorf("invalid address response")
+	}
+
+	return common.BytesToAddress(result[12:32]), nil
+}
+
+// getCompoundMarkets returns the cToken markets listed by the Comptroller.
+// The list is loaded at the latest block; the Comptroller never removes
+// markets from it, so it also covers older ranges. A failed lookup isn't
+// cached, so the next range retries it.
+func (d *Detector) getCompoundMarkets(ctx context.Context) ([]common.Address, error) {
+	d.mu.RLock()
+	markets := d.compoundMarkets
+	d.mu.RUnlock()
+	if markets != nil {
+		return markets, nil
+	}
+
+	// getAllMarkets() selector: 0xb0772d0b
+	msg := ethereum.CallMsg{
+		To:   &CompoundComptroller,
+		Data: common.Hex2Bytes("b0772d0b"),
+	}
+
+	result, err := d.client.CallContract(ctx, msg, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// ABI address[]: offset, length, then one word per address
+	if len(result) < 64 {
+		return nil, fmt.Errorf("invalid getAllMarkets response")
+	}
+	count := new(big.Int).SetBytes(result[32:64])
+	if !count.IsUint64() || uint64(len(result)-64) < count.Uint64()*32 {
+		return nil, fmt.Errorf("invalid getAllMarkets response")
+	}
+
+	markets = make([]common.Address, 0, count.Uint64())
+	for idx := uint64(0); idx < count.Uint64(); idx++ {
+		word := result[64+idx*32 : 96+idx*32]
+		markets = append(markets, common.BytesToAddress(word[12:32]))
+	}
+
+	d.mu.Lock()
+	d.compoundMarkets = markets
+	d.mu.Unlock()
+
+	log.Debug().Int("markets", len(markets)).Msg("Cached Compound V2 markets")
+
+	return markets, nil
+}
+
+// addCompoundMarkets adds newly listed cToken markets to the cached list
+// and returns the ones that weren't known yet
+func (d *Detector) addCompoundMarkets(listed []common.Address) []common.Address {
+	if len(listed) == 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	known := make(map[common.Address]bool, len(d.compoundMarkets))
+	for _, market := range d.compoundMarkets {
+		known[market] = true
+	}
+
+	var added []common.Address
+	for _, market := range listed {
+		if known[market] {
+			continue
+		}
+		known[market] = true
+		added = append(added, market)
+	}
+
+	if len(added) > 0 {
+		// Copy so slices already handed out aren't appended to
+		d.compoundMarkets = append(append([]common.Address(nil), d.compoundMarkets...), added...)
+		log.Debug().Int("markets", len(added)).Msg("Added newly listed Compound V2 markets")
+	}
+
+	return added
+}
+
+// callProtocolSeizeShare calls protocolSeizeShareMantissa() on a cToken at a
+// block. cTokens deployed before the protocol took a share don't have the
+// getter and revert, which means a share of zero.
+func (d *Detector) callProtocolSeizeShare(ctx context.Context, cToken common.Address, blockNumber uint64) (*big.Int, error) {
+	// protocolSeizeShareMantissa() selector: 0x6752e702
+	msg := ethereum.CallMsg{
+		To:   &cToken,
+		Data: common.Hex2Bytes("6752e702"),
+	}
+
+	result, err := d.client.CallContract(ctx, msg, new(big.Int).SetUint64(blockNumber))
+	if eth.IsRevertError(err) {
+		return big.NewInt(0), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) < 32 {
+		return big.NewInt(0), nil
+	}
+
+	return new(big.Int).SetBytes(result[0:32]), nil
+}
+
+// callExchangeRateStored calls exchangeRateStored() on a cToken at a block
+func (d *Detector) callExchangeRateStored(ctx context.Context, cToken common.Address, blockNumber uint64) (*big.Int, error) {
+	// exchangeRateStored() selector: 0x182df0f5
+	msg := ethereum.CallMsg{
+		To:   &cToken,
+		Data: common.Hex2Bytes("182df0f5"),
+	}
+
+	result, err := d.client.CallContract(ctx, msg, new(big.Int).SetUint64(blockNumber))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) < 32 {
+		return nil, fmt.Errorf("invalid exchangeRateStored response")
+	}
+
+	return new(big.Int).SetBytes(result[0:32]), nil
+}
@@ -0,0 +1,290 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+const namespace = "mev_inspector"
+
+// Registry holds every inspector metric
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+// Pipeline metrics
+var (
+	BlocksProcessed = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "blocks_processed_total",
+		Help:      "Blocks fully processed.",
+	})
+
+	BlockProcessingSeconds = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "block_processing_seconds",
+		Help:      "Time spent decoding and detecting the transactions of a block.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	})
+
+	SwapsDecoded = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "swaps_decoded_total",
+		Help:      "Swaps decoded, by protocol.",
+	}, []string{"protocol"})
+
+	Arbitrages = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "arbitrages_total",
+		Help:      "Arbitrages reported, by type.",
+	}, []string{"type"})
+
+	ArbitrageProfitETH = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "arbitrage_profit_eth_total",
+		Help:      "Gross arbitrage profit valued in ETH at the prices of its block.",
+	})
+
+	ArbitrageProfitUSD = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "arbitrage_profit_usd_total",
+		Help:      "Gross arbitrage profit valued in US dollars at the prices of its block.",
+	})
+
+	ArbitrageUnpriced = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "arbitrages_unpriced_total",
+		Help:      "Arbitrages whose profit token couldn't be valued in ETH.",
+	})
+
+	ArbitrageNetProfitETH = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "arbitrage_net_profit_eth_total",
+		Help:      "Arbitrage profit after gas and flash loan fees, in ETH. Losses are not subtracted.",
+	})
+
+	ArbitragePriorityFeesETH = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "arbitrage_priority_fees_eth_total",
+		Help:      "Priority fees paid to block proposers by arbitrage transactions, in ETH.",
+	})
+
+	ArbitrageBurnedFeesETH = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "arbitrage_burned_fees_eth_total",
+		Help:      "Base and blob fees burned by arbitrage transactions, in ETH.",
+	})
+
+	Sandwiches = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sandwiches_total",
+		Help:      "Sandwich attacks detected.",
+	})
+
+	Liquidations = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "liquidations_total",
+		Help:      "Liquidations detected, by protocol.",
+	}, []string{"protocol"})
+
+	Reorgs = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reorgs_total",
+		Help:      "Chain reorganizations that replaced processed blocks.",
+	})
+
+	HeadBlock = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "head_block",
+		Help:      "Latest block number reported by the node.",
+	})
+
+	LastBlock = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_processed_block",
+		Help:      "Last fully processed block number.",
+	})
+
+	HeadLag = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "head_lag_blocks",
+		Help:      "Chain head minus the last processed block.",
+	})
+
+	HeadSubscriptionActive = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "head_subscription_active",
+		Help:      "Whether new heads arrive over the WebSocket subscription (1) or by polling (0).",
+	})
+
+	HeadSubscriptionDrops = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "head_subscription_drops_total",
+		Help:      "WebSocket head subscriptions that failed or were lost.",
+	})
+)
+
+// RPC metrics
+var (
+	RPCRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_requests_total",
+		Help:      "RPC calls made, by method. Retries count as separate calls.",
+	}, []string{"method"})
+
+	RPCErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_errors_total",
+		Help:      "Failed RPC calls, by method.",
+	}, []string{"method"})
+
+	RPCLatency = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_request_duration_seconds",
+		Help:      "RPC call latency, by method.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	}, []string{"method"})
+
+	LogRangeSplits = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_log_range_splits_total",
+		Help:      "eth_getLogs ranges split in half after the provider rejected them.",
+	})
+
+	RPCHedgedRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_hedged_requests_total",
+		Help:      "Calls also sent to a second endpoint because the first was slow, by method.",
+	}, []string{"method"})
+
+	RPCEndpointHealthy = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rpc_endpoint_healthy",
+		Help:      "Whether an RPC endpoint is in rotation (1) or not (0), by endpoint.",
+	}, []string{"endpoint"})
+
+	RPCEndpointHead = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rpc_endpoint_head_block",
+		Help:      "Block height reported by an RPC endpoint at its last health check, by endpoint.",
+	}, []string{"endpoint"})
+)
+
+// Cache metrics
+var (
+	PoolCacheHits = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pool_cache_hits_total",
+		Help:      "Pool metadata lookups served from cache, by cache.",
+	}, []string{"cache"})
+
+	PoolCacheMisses = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pool_cache_misses_total",
+		Help:      "Pool metadata lookups that required RPC calls, by cache.",
+	}, []string{"cache"})
+
+	TokenCacheHits = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_cache_hits_total",
+		Help:      "Token metadata lookups served from cache.",
+	})
+
+	TokenCacheMisses = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_cache_misses_total",
+		Help:      "Token metadata lookups that required RPC calls.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// ObserveRPC records one RPC call started at start
+func ObserveRPC(method string, start time.Time, err error) {
+	RPCRequests.WithLabelValues(method).Inc()
+	RPCLatency.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		RPCErrors.WithLabelValues(method).Inc()
+	}
+}
+
+// PoolCacheLookup records a pool cache hit or miss
+func PoolCacheLookup(cache string, hit bool) {
+	if hit {
+		PoolCacheHits.WithLabelValues(cache).Inc()
+	} else {
+		PoolCacheMisses.WithLabelValues(cache).Inc()
+	}
+}
+
+// TokenCacheLookup records a token cache hit or miss
+func TokenCacheLookup(hit bool) {
+	if hit {
+		TokenCacheHits.Inc()
+	} else {
+		TokenCacheMisses.Inc()
+	}
+}
+
+// SetHead records the chain head and last processed block
+func SetHead(head, lastBlock uint64) {
+	HeadBlock.Set(float64(head))
+	LastBlock.Set(float64(lastBlock))
+	if head > lastBlock {
+		HeadLag.Set(float64(head - lastBlock))
+	} else {
+		HeadLag.Set(0)
+	}
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Serve exposes /metrics on addr until ctx is cancelled
+func Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	log.Info().Str("addr", addr).Msg("Metrics server listening")
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("metrics server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to shut down metrics server: %w", err)
+	}
+	return nil
+}
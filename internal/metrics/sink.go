@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"math/big"
+
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+// Sink is an output sink that updates the pipeline metrics from published
+// results
+type Sink struct{}
+
+// NewSink creates a metrics sink
+func NewSink() *Sink {
+	return &Sink{}
+}
+
+// Name returns the sink name
+func (s *Sink) Name() string {
+	return "metrics"
+}
+
+// Durable implements output.Durable so no result goes uncounted when the
+// queue fills up. The sink never fails, so it doesn't hold back checkpoints.
+func (s *Sink) Durable() bool {
+	return true
+}
+
+// OnSwap counts a decoded swap
+func (s *Sink) OnSwap(swap *types.Swap) error {
+	SwapsDecoded.WithLabelValues(swap.Protocol).Inc()
+	return nil
+}
+
+// OnArbitrage counts an arbitrage and adds its profit
+func (s *Sink) OnArbitrage(arb *types.Arbitrage) error {
+	Arbitrages.WithLabelValues(string(arb.Type)).Inc()
+
+	if arb.ProfitETH == nil {
+		ArbitrageUnpriced.Inc()
+	} else if arb.ProfitETH.Sign() > 0 {
+		ArbitrageProfitETH.Add(toUnits(arb.ProfitETH, 18))
+	}
+	if arb.ProfitUSD != nil && *arb.ProfitUSD > 0 {
+		ArbitrageProfitUSD.Add(*arb.ProfitUSD)
+	}
+	if arb.NetProfitWei != nil && arb.NetProfitWei.Sign() > 0 {
+		ArbitrageNetProfitETH.Add(toUnits(arb.NetProfitWei, 18))
+	}
+	if arb.PriorityFee != nil {
+		ArbitragePriorityFeesETH.Add(toUnits(arb.PriorityFee, 18))
+	}
+	if arb.BurnedFee != nil {
+		ArbitrageBurnedFeesETH.Add(toUnits(arb.BurnedFee, 18))
+	}
+	if arb.BlobFee != nil {
+		ArbitrageBurnedFeesETH.Add(toUnits(arb.BlobFee, 18))
+	}
+	return nil
+}
+
+// OnBlock counts a processed block and its sandwiches and liquidations
+func (s *Sink) OnBlock(block *types.BlockResult) error {
+	BlocksProcessed.Inc()
+	BlockProcessingSeconds.Observe(block.ProcessingTime.Seconds())
+
+	Sandwiches.Add(float64(len(block.Sandwiches)))
+	for _, liq := range block.Liquidations {
+		Liquidations.WithLabelValues(liq.Protocol).Inc()
+	}
+	return nil
+}
+
+// OnReorg counts a reorg
+func (s *Sink) OnReorg(ancestor, tip uint64, retracted []*types.BlockResult) error {
+	Reorgs.Inc()
+	return nil
+}
+
+// OnError implements output.Sink
+func (s *Sink) OnError(err error, context string) error {
+	return nil
+}
+
+// Close implements output.Sink
+func (s *Sink) Close() error {
+	return nil
+}
+
+// toUnits converts a raw token amount to whole units
+func toUnits(amount *big.Int, decimals int) float64 {
+	value := new(big.Float).SetInt(amount)
+	value.Quo(value, new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)))
+	f, _ := value.Float64()
+	return f
+}
@@ -0,0 +1,97 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+// FileSink appends every event to a file as one JSON object per line
+type FileSink struct {
+	file *os.File
+	w    *bufio.Writer
+	enc  *json.Encoder
+}
+
+// NewFileSink opens path for appending, creating it if needed
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output file: %w", err)
+	}
+
+	w := bufio.NewWriter(file)
+	return &FileSink{
+		file: file,
+		w:    w,
+		enc:  json.NewEncoder(w),
+	}, nil
+}
+
+// Name returns the sink name
+func (f *FileSink) Name() string {
+	return "file"
+}
+
+// OnSwap writes a "swap" event
+func (f *FileSink) OnSwap(swap *types.Swap) error {
+	return f.write("swap", swap)
+}
+
+// OnArbitrage writes an "arbitrage" event
+func (f *FileSink) OnArbitrage(arb *types.Arbitrage) error {
+	return f.write("arbitrage", arb)
+}
+
+// OnBlock writes a "block" event and flushes buffered events
+func (f *FileSink) OnBlock(block *types.BlockResult) error {
+	if err := f.write("block", block); err != nil {
+		return err
+	}
+	return f.w.Flush()
+}
+
+// OnReorg writes a "reorg" event
+func (f *FileSink) OnReorg(ancestor, tip uint64, retracted []*types.BlockResult) error {
+	if err := f.write("reorg", ReorgEvent{
+		CommonAncestor: ancestor,
+		ReplacedTo:     tip,
+		Retracted:      retracted,
+	}); err != nil {
+		return err
+	}
+	return f.w.Flush()
+}
+
+// OnError writes an "error" event
+func (f *FileSink) OnError(err error, context string) error {
+	return f.write("error", ErrorEvent{
+		Context: context,
+		Error:   err.Error(),
+	})
+}
+
+// Close flushes pending events and closes the file
+func (f *FileSink) Close() error {
+	if err := f.w.Flush(); err != nil {
+		f.file.Close()
+		return fmt.Errorf("failed to flush output file: %w", err)
+	}
+	return f.file.Close()
+}
+
+// write encodes one event
+func (f *FileSink) write(eventType string, data interface{}) error {
+	if err := f.enc.Encode(Event{
+		Type: eventType,
+		Time: time.Now().UTC(),
+		Data: data,
+	}); err != nil {
+		return fmt.Errorf("failed to write %s event: %w", eventType, err)
+	}
+	return nil
+}
@@ -4,33 +4,45 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
 	"github.com/devlongs/mev-inspector/internal/config"
+	"github.com/devlongs/mev-inspector/internal/token"
 	"github.com/devlongs/mev-inspector/pkg/types"
 )
 
-// Logger handles output formatting for detected MEV
+// Logger is a Sink that writes detected MEV to zerolog and keeps statistics
 type Logger struct {
-	stats *Stats
+	log    zerolog.Logger
+	tokens *token.Registry // Renders symbols and amounts; nil = raw values
+	stats  *Stats
+	mu     sync.Mutex
 }
 
 // Stats tracks MEV detection statistics
 type Stats struct {
-	BlocksProcessed uint64
-	SwapsDetected   uint64
-	ArbitragesFound uint64
-	TotalProfitWei  *big.Int
-	TotalNetProfit  *big.Int
-	StartTime       time.Time
+	BlocksProcessed   uint64
+	SwapsDetected     uint64
+	ArbitragesFound   uint64
+	SandwichesFound   uint64
+	LiquidationsFound uint64
+	FlashLoanArbs     uint64 // Arbitrages funded by flash loans
+	ReorgsDetected    uint64
+	ResultsRetracted  uint64
+	UnpricedArbs      uint64   // Arbitrages whose profit token couldn't be valued in ETH
+	TotalProfitWei    *big.Int // Gross profit of priced arbitrages, in ETH
+	TotalNetProfit    *big.Int // Net profit of priced arbitrages, in ETH
+	TotalProfitUSD    float64
+	StartTime         time.Time
 }
 
-// NewLogger creates a new MEV logger
-func NewLogger(cfg config.LoggingConfig) *Logger {
-	// Configure zerolog
+// ConfigureLogging sets the global zerolog format and level
+func ConfigureLogging(cfg config.LoggingConfig) {
 	switch cfg.Format {
 	case "json":
 		// Default JSON output
@@ -52,8 +64,20 @@ func NewLogger(cfg config.LoggingConfig) *Logger {
 	case "error":
 		zerolog.SetGlobalLevel(zerolog.ErrorLevel)
 	}
+}
+
+// NewLogger creates a new MEV logger. A disabled logger writes nothing but
+// still keeps statistics. Tokens are rendered with the metadata already
+// resolved by tokens.
+func NewLogger(enabled bool, tokens *token.Registry) *Logger {
+	logger := log.Logger
+	if !enabled {
+		logger = zerolog.Nop()
+	}
 
 	return &Logger{
+		log:    logger,
+		tokens: tokens,
 		stats: &Stats{
 			TotalProfitWei: big.NewInt(0),
 			TotalNetProfit: big.NewInt(0),
@@ -62,56 +86,284 @@ func NewLogger(cfg config.LoggingConfig) *Logger {
 	}
 }
 
+// Name returns the sink name
+func (l *Logger) Name() string {
+	return "log"
+}
+
+// OnSwap logs a decoded swap
+func (l *Logger) OnSwap(swap *types.Swap) error {
+	l.LogSwap(swap)
+	return nil
+}
+
+// OnArbitrage logs a detected arbitrage
+func (l *Logger) OnArbitrage(arb *types.Arbitrage) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.LogArbitrage(arb)
+	return nil
+}
+
+// OnBlock logs the sandwiches and liquidations of a processed block followed
+// by its completion summary
+func (l *Logger) OnBlock(block *types.BlockResult) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for idx := range block.Sandwiches {
+		l.LogSandwich(&block.Sandwiches[idx])
+	}
+	for idx := range block.Liquidations {
+		l.LogLiquidation(&block.Liquidations[idx])
+	}
+	l.LogBlockComplete(block)
+	return nil
+}
+
+// OnReorg logs a reorg and the results retracted by it
+func (l *Logger) OnReorg(ancestor, tip uint64, retracted []*types.BlockResult) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.LogReorg(ancestor, tip)
+	for _, block := range retracted {
+		l.LogRetracted(block)
+	}
+	return nil
+}
+
+// OnError logs a processing error
+func (l *Logger) OnError(err error, context string) error {
+	l.LogError(err, context)
+	return nil
+}
+
+// Close implements Sink
+func (l *Logger) Close() error {
+	return nil
+}
+
 // LogBlockStart logs the start of block processing
 func (l *Logger) LogBlockStart(blockNumber uint64, txCount int) {
-	log.Debug().
+	l.log.Debug().
 		Uint64("block", blockNumber).
 		Int("txCount", txCount).
 		Msg("Processing block")
 }
 
-// LogBlockComplete logs completion of block processing
-func (l *Logger) LogBlockComplete(blockNumber uint64, swaps int, arbs int, duration time.Duration) {
+// LogBlockComplete logs completion of block processing with the swaps and
+// arbitrages attributed to that block
+func (l *Logger) LogBlockComplete(block *types.BlockResult) {
 	l.stats.BlocksProcessed++
-	l.stats.SwapsDetected += uint64(swaps)
-	l.stats.ArbitragesFound += uint64(arbs)
+	l.stats.SwapsDetected += uint64(len(block.Swaps))
+	l.stats.ArbitragesFound += uint64(len(block.Arbitrages))
 
-	log.Info().
-		Uint64("block", blockNumber).
-		Int("swaps", swaps).
-		Int("arbitrages", arbs).
-		Dur("duration", duration).
+	// Only priced profits can be summed
+	profit := big.NewInt(0)
+	netProfit := big.NewInt(0)
+	var profitUSD float64
+	for _, arb := range block.Arbitrages {
+		if arb.ProfitETH != nil {
+			profit.Add(profit, arb.ProfitETH)
+		}
+		if arb.NetProfitWei != nil {
+			netProfit.Add(netProfit, arb.NetProfitWei)
+		}
+		if arb.ProfitUSD != nil {
+			profitUSD += *arb.ProfitUSD
+		}
+	}
+
+	l.log.Info().
+		Uint64("block", block.Number).
+		Int("swaps", len(block.Swaps)).
+		Int("arbitrages", len(block.Arbitrages)).
+		Str("profit", weiToEther(profit)+" ETH").
+		Str("netProfit", weiToEther(netProfit)+" ETH").
+		Str("profitUSD", formatUSD(profitUSD)).
+		Dur("duration", block.ProcessingTime).
 		Msg("Block processed")
 }
 
 // LogArbitrage logs a detected arbitrage
 func (l *Logger) LogArbitrage(arb *types.Arbitrage) {
-	profitETH := weiToEther(arb.Profit)
+	l.stats.countArbitrage(arb, false)
+
+	profitETH := "N/A"
+	if arb.ProfitETH != nil {
+		profitETH = weiToEther(arb.ProfitETH)
+	}
 	netProfitETH := "N/A"
 	if arb.NetProfitWei != nil {
 		netProfitETH = weiToEther(arb.NetProfitWei)
-		l.stats.TotalNetProfit.Add(l.stats.TotalNetProfit, arb.NetProfitWei)
 	}
-	l.stats.TotalProfitWei.Add(l.stats.TotalProfitWei, arb.Profit)
+	profitUSD := "N/A"
+	if arb.ProfitUSD != nil {
+		profitUSD = formatUSD(*arb.ProfitUSD)
+	}
 
 	// Build path string
-	path := buildPathString(arb.Path)
+	path := buildPathString(arb.Path, l.tokens)
 
-	log.Info().
+	event := l.log.Info().
 		Str("txHash", arb.TxHash.Hex()).
 		Uint64("block", arb.BlockNumber).
 		Str("arbitrageur", arb.Arbitrageur.Hex()).
+		Str("amountIn", l.tokens.FormatAmount(arb.TokenStart, arb.AmountIn)).
+		Str("amountOut", l.tokens.FormatAmount(arb.TokenEnd, arb.AmountOut)).
+		Str("profit", l.tokens.FormatAmount(arb.ProfitToken, arb.Profit)).
 		Str("profitETH", profitETH).
 		Str("netProfitETH", netProfitETH).
+		Str("profitUSD", profitUSD).
 		Uint64("gasUsed", arb.GasUsed).
 		Str("path", path).
 		Int("hops", len(arb.Path)).
-		Msg("ARBITRAGE DETECTED")
+		Bool("flashLoan", len(arb.FlashLoans) > 0)
+
+	if arb.PriorityFee != nil {
+		event = event.
+			Str("priorityFeeETH", weiToEther(arb.PriorityFee)).
+			Str("burnedFeeETH", weiToEther(arb.BurnedFee))
+	}
+	if arb.BlobFee != nil {
+		event = event.Str("blobFeeETH", weiToEther(arb.BlobFee))
+	}
+
+	if len(arb.FlashLoans) > 0 {
+		lenders := make([]string, 0, len(arb.FlashLoans))
+		for _, loan := range arb.FlashLoans {
+			lenders = append(lenders, loan.Protocol+":"+l.tokens.FormatAmount(loan.Token, loan.Amount))
+		}
+		event = event.
+			Strs("flashLoans", lenders).
+			Str("flashLoanFees", l.tokens.FormatAmount(arb.ProfitToken, arb.FlashLoanFees))
+	}
+
+	event.Msg("ARBITRAGE DETECTED")
+}
+
+// LogSandwich logs a detected sandwich attack
+func (l *Logger) LogSandwich(sandwich *types.Sandwich) {
+	l.stats.SandwichesFound++
+
+	victimTxs := make([]string, 0, len(sandwich.Victims))
+	for _, victim := range sandwich.Victims {
+		victimTxs = append(victimTxs, victim.TxHash.Hex())
+	}
+
+	event := l.log.Info().
+		Uint64("block", sandwich.BlockNumber).
+		Str("attacker", sandwich.Attacker.Hex()).
+		Str("pool", sandwich.Pool.Hex())
+	if sandwich.PoolID != (common.Hash{}) {
+		event = event.Str("poolId", sandwich.PoolID.Hex())
+	}
+	event.
+		Str("frontrunTx", sandwich.Frontrun.TxHash.Hex()).
+		Str("backrunTx", sandwich.Backrun.TxHash.Hex()).
+		Strs("victimTxs", victimTxs).
+		Str("profitToken", sandwich.ProfitToken.Hex()).
+		Str("profit", l.tokens.FormatAmount(sandwich.ProfitToken, sandwich.Profit)).
+		Str("victimLoss", l.tokens.FormatAmount(sandwich.ProfitToken, sandwich.VictimLoss)).
+		Msg("SANDWICH DETECTED")
+}
+
+// LogLiquidation logs a detected liquidation
+func (l *Logger) LogLiquidation(liq *types.Liquidation) {
+	l.stats.LiquidationsFound++
+
+	profit := "N/A"
+	if liq.Profit != nil {
+		profit = l.tokens.FormatAmount(liq.ProfitToken, liq.Profit)
+	}
+
+	l.log.Info().
+		Str("txHash", liq.TxHash.Hex()).
+		Uint64("block", liq.BlockNumber).
+		Str("protocol", liq.Protocol).
+		Str("liquidator", liq.Liquidator.Hex()).
+		Str("borrower", liq.Borrower.Hex()).
+		Bool("absorbed", liq.Absorbed).
+		Str("collateralAsset", liq.CollateralAsset.Hex()).
+		Str("collateralReceived", l.tokens.FormatAmount(liq.CollateralAsset, liq.CollateralAmount)).
+		Str("debtAsset", liq.DebtAsset.Hex()).
+		Str("debtRepaid", l.tokens.FormatAmount(liq.DebtAsset, liq.DebtRepaid)).
+		Int("swaps", len(liq.Swaps)).
+		Str("profit", profit).
+		Msg("LIQUIDATION DETECTED")
+}
+
+// LogReorg logs a chain reorganization that replaced already processed blocks
+func (l *Logger) LogReorg(ancestor uint64, replacedTo uint64) {
+	l.stats.ReorgsDetected++
+
+	l.log.Warn().
+		Uint64("commonAncestor", ancestor).
+		Uint64("replacedFrom", ancestor+1).
+		Uint64("replacedTo", replacedTo).
+		Msg("Chain reorganization detected")
+}
+
+// LogRetracted logs every result of a block that was replaced by a reorg so
+// downstream consumers can remove it, and takes the block out of the
+// statistics; it is counted again once its replacement is processed
+func (l *Logger) LogRetracted(block *types.BlockResult) {
+	l.stats.BlocksProcessed--
+	l.stats.SwapsDetected -= uint64(len(block.Swaps))
+	l.stats.ArbitragesFound -= uint64(len(block.Arbitrages))
+	l.stats.SandwichesFound -= uint64(len(block.Sandwiches))
+	l.stats.LiquidationsFound -= uint64(len(block.Liquidations))
+	for idx := range block.Arbitrages {
+		l.stats.countArbitrage(&block.Arbitrages[idx], true)
+	}
+
+	for _, swap := range block.Swaps {
+		l.stats.ResultsRetracted++
+		l.log.Debug().
+			Str("kind", "swap").
+			Str("txHash", swap.TxHash.Hex()).
+			Uint("logIndex", swap.LogIndex).
+			Uint64("block", block.Number).
+			Str("blockHash", block.Hash.Hex()).
+			Msg("RETRACTED")
+	}
+
+	for _, arb := range block.Arbitrages {
+		l.stats.ResultsRetracted++
+		l.log.Warn().
+			Str("kind", "arbitrage").
+			Str("txHash", arb.TxHash.Hex()).
+			Uint64("block", block.Number).
+			Str("blockHash", block.Hash.Hex()).
+			Msg("RETRACTED")
+	}
+
+	for _, sandwich := range block.Sandwiches {
+		l.stats.ResultsRetracted++
+		l.log.Warn().
+			Str("kind", "sandwich").
+			Str("txHash", sandwich.Frontrun.TxHash.Hex()).
+			Uint64("block", block.Number).
+			Str("blockHash", block.Hash.Hex()).
+			Msg("RETRACTED")
+	}
+
+	for _, liq := range block.Liquidations {
+		l.stats.ResultsRetracted++
+		l.log.Warn().
+			Str("kind", "liquidation").
+			Str("txHash", liq.TxHash.Hex()).
+			Uint64("block", block.Number).
+			Str("blockHash", block.Hash.Hex()).
+			Msg("RETRACTED")
+	}
 }
 
 // LogSwap logs a single swap event (debug level)
 func (l *Logger) LogSwap(swap *types.Swap) {
-	log.Debug().
+	l.log.Debug().
 		Str("txHash", swap.TxHash.Hex()).
 		Str("pool", swap.Pool.Hex()).
 		Str("protocol", swap.Protocol).
@@ -122,15 +374,24 @@ func (l *Logger) LogSwap(swap *types.Swap) {
 
 // LogStats logs current statistics
 func (l *Logger) LogStats() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	elapsed := time.Since(l.stats.StartTime)
 	blocksPerSec := float64(l.stats.BlocksProcessed) / elapsed.Seconds()
 
-	log.Info().
+	l.log.Info().
 		Uint64("blocksProcessed", l.stats.BlocksProcessed).
 		Uint64("swapsDetected", l.stats.SwapsDetected).
 		Uint64("arbitragesFound", l.stats.ArbitragesFound).
+		Uint64("sandwichesFound", l.stats.SandwichesFound).
+		Uint64("liquidationsFound", l.stats.LiquidationsFound).
+		Uint64("flashLoanArbs", l.stats.FlashLoanArbs).
+		Uint64("reorgsDetected", l.stats.ReorgsDetected).
 		Str("totalProfit", weiToEther(l.stats.TotalProfitWei)+" ETH").
 		Str("totalNetProfit", weiToEther(l.stats.TotalNetProfit)+" ETH").
+		Str("totalProfitUSD", formatUSD(l.stats.TotalProfitUSD)).
+		Uint64("unpricedArbs", l.stats.UnpricedArbs).
 		Float64("blocksPerSec", blocksPerSec).
 		Dur("uptime", elapsed).
 		Msg("MEV Inspector Stats")
@@ -138,15 +399,59 @@ func (l *Logger) LogStats() {
 
 // LogError logs an error
 func (l *Logger) LogError(err error, context string) {
-	log.Error().
+	l.log.Error().
 		Err(err).
 		Str("context", context).
 		Msg("Error occurred")
 }
 
-// GetStats returns current statistics
-func (l *Logger) GetStats() *Stats {
-	return l.stats
+// countArbitrage adds an arbitrage to the totals, or takes it out again when
+// a reorg retracts it
+func (s *Stats) countArbitrage(arb *types.Arbitrage, retract bool) {
+	add := func(total, amount *big.Int) {
+		if retract {
+			total.Sub(total, amount)
+		} else {
+			total.Add(total, amount)
+		}
+	}
+	count := func(counter *uint64) {
+		if retract {
+			*counter--
+		} else {
+			*counter++
+		}
+	}
+
+	if arb.ProfitETH != nil {
+		add(s.TotalProfitWei, arb.ProfitETH)
+	} else {
+		count(&s.UnpricedArbs)
+	}
+	if arb.NetProfitWei != nil {
+		add(s.TotalNetProfit, arb.NetProfitWei)
+	}
+	if arb.ProfitUSD != nil {
+		if retract {
+			s.TotalProfitUSD -= *arb.ProfitUSD
+		} else {
+			s.TotalProfitUSD += *arb.ProfitUSD
+		}
+	}
+	if len(arb.FlashLoans) > 0 {
+		count(&s.FlashLoanArbs)
+	}
+}
+
+// GetStats returns a snapshot of the current statistics
+func (l *Logger) GetStats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := *l.stats
+	stats.TotalProfitWei = new(big.Int).Set(l.stats.TotalProfitWei)
+	stats.TotalNetProfit = new(big.Int).Set(l.stats.TotalNetProfit)
+	return stats
 }
 
 // weiToEther converts wei to ether string with 6 decimal places
@@ -163,8 +468,14 @@ func weiToEther(wei *big.Int) string {
 	return fmt.Sprintf("%.6f", ether)
 }
 
-// buildPathString creates a human-readable path string showing token flow
-func buildPathString(swaps []types.Swap) string {
+// formatUSD formats a dollar amount with cents
+func formatUSD(usd float64) string {
+	return fmt.Sprintf("$%.2f", usd)
+}
+
+// buildPathString creates a human-readable path string showing token flow,
+// e.g. "WETH -> USDC -> WETH"
+func buildPathString(swaps []types.Swap, tokens *token.Registry) string {
 	if len(swaps) == 0 {
 		return ""
 	}
@@ -176,19 +487,19 @@ func buildPathString(swaps []types.Swap) string {
 
 		// Determine token in/out based on amounts
 		if swap.Amount0In != nil && swap.Amount0In.Sign() > 0 {
-			tokenIn = swap.Token0.Hex()[:10]
-			tokenOut = swap.Token1.Hex()[:10]
+			tokenIn = tokens.Symbol(swap.Token0)
+			tokenOut = tokens.Symbol(swap.Token1)
 		} else if swap.Amount1In != nil && swap.Amount1In.Sign() > 0 {
-			tokenIn = swap.Token1.Hex()[:10]
-			tokenOut = swap.Token0.Hex()[:10]
+			tokenIn = tokens.Symbol(swap.Token1)
+			tokenOut = tokens.Symbol(swap.Token0)
 		} else {
 			// Fallback: check output amounts
 			if swap.Amount0Out != nil && swap.Amount0Out.Sign() > 0 {
-				tokenIn = swap.Token1.Hex()[:10]
-				tokenOut = swap.Token0.Hex()[:10]
+				tokenIn = tokens.Symbol(swap.Token1)
+				tokenOut = tokens.Symbol(swap.Token0)
 			} else {
-				tokenIn = swap.Token0.Hex()[:10]
-				tokenOut = swap.Token1.Hex()[:10]
+				tokenIn = tokens.Symbol(swap.Token0)
+				tokenOut = tokens.Symbol(swap.Token1)
 			}
 		}
 
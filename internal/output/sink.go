@@ -0,0 +1,318 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+// Sink consumes inspection results. Each sink receives events in the order
+// they were published, from a single goroutine.
+type Sink interface {
+	// Name identifies the sink in logs
+	Name() string
+	// OnSwap is called for every decoded swap
+	OnSwap(swap *types.Swap) error
+	// OnArbitrage is called for every reported arbitrage
+	OnArbitrage(arb *types.Arbitrage) error
+	// OnBlock is called once a block has been fully processed
+	OnBlock(block *types.BlockResult) error
+	// OnReorg is called when blocks above ancestor were replaced; retracted
+	// holds the previously published results of those blocks
+	OnReorg(ancestor, tip uint64, retracted []*types.BlockResult) error
+	// OnError is called for processing errors
+	OnError(err error, context string) error
+	// Close flushes and releases the sink
+	Close() error
+}
+
+// Durable is implemented by sinks whose output is a record that must be
+// complete, such as the database or a webhook. Events for a durable sink are
+// never dropped, and Dispatcher.Flush reports its failures so the caller
+// only treats blocks as done once the sink has them.
+type Durable interface {
+	Durable() bool
+}
+
+// Event is the serialized form of a sink event used by file and network sinks
+type Event struct {
+	Type string      `json:"type"` // "swap", "arbitrage", "block", "reorg" or "error"
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+// ReorgEvent is the payload of a "reorg" event
+type ReorgEvent struct {
+	CommonAncestor uint64               `json:"commonAncestor"`
+	ReplacedTo     uint64               `json:"replacedTo"`
+	Retracted      []*types.BlockResult `json:"retracted"`
+}
+
+// ErrorEvent is the payload of an "error" event
+type ErrorEvent struct {
+	Context string `json:"context"`
+	Error   string `json:"error"`
+}
+
+// Dispatcher fans events out to every sink. Each sink has its own bounded
+// queue and goroutine, so a slow or failing sink doesn't hold up the other
+// sinks. When the queue of a durable sink is full publishing waits for room;
+// other sinks have new events dropped instead, so they never block the
+// caller.
+type Dispatcher struct {
+	workers []*sinkWorker
+	wg      sync.WaitGroup
+
+	// Blocks a durable sink failed. When they are published again they only
+	// go to the sinks that failed them, so the others don't count them twice.
+	mu       sync.Mutex
+	retrying map[uint64]bool
+}
+
+// sinkWorker delivers queued events to one sink
+type sinkWorker struct {
+	sink    Sink
+	durable bool
+	events  chan func(Sink) error
+	dropped atomic.Uint64
+
+	// Failures of a durable sink since the last Flush
+	mu       sync.Mutex
+	failed   int
+	firstErr error
+
+	// Blocks queued since the last Flush, and blocks the sink failed and
+	// still needs; guarded by the Dispatcher's mu
+	published []uint64
+	redo      map[uint64]bool
+}
+
+// NewDispatcher starts a delivery goroutine per sink with room for
+// queueSize pending events each
+func NewDispatcher(queueSize int, sinks ...Sink) *Dispatcher {
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	d := &Dispatcher{retrying: make(map[uint64]bool)}
+	for _, sink := range sinks {
+		w := &sinkWorker{
+			sink:   sink,
+			events: make(chan func(Sink) error, queueSize),
+			redo:   make(map[uint64]bool),
+		}
+		if durable, ok := sink.(Durable); ok {
+			w.durable = durable.Durable()
+		}
+		d.workers = append(d.workers, w)
+
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			w.run()
+		}()
+	}
+
+	return d
+}
+
+// OnSwap publishes a decoded swap
+func (d *Dispatcher) OnSwap(swap *types.Swap) {
+	s := *swap
+	d.publishBlock(s.BlockNumber, false, func(sink Sink) error { return sink.OnSwap(&s) })
+}
+
+// OnArbitrage publishes a reported arbitrage
+func (d *Dispatcher) OnArbitrage(arb *types.Arbitrage) {
+	a := *arb
+	d.publishBlock(a.BlockNumber, false, func(sink Sink) error { return sink.OnArbitrage(&a) })
+}
+
+// OnBlock publishes a fully processed block, after its swaps and
+// arbitrages. The block must not be modified afterwards.
+func (d *Dispatcher) OnBlock(block *types.BlockResult) {
+	d.publishBlock(block.Number, true, func(sink Sink) error { return sink.OnBlock(block) })
+}
+
+// OnReorg publishes a reorg and the results it retracted. Failed blocks
+// above the ancestor were replaced, so every sink gets them again.
+func (d *Dispatcher) OnReorg(ancestor, tip uint64, retracted []*types.BlockResult) {
+	d.mu.Lock()
+	for number := range d.retrying {
+		if number > ancestor {
+			delete(d.retrying, number)
+		}
+	}
+	for _, w := range d.workers {
+		for number := range w.redo {
+			if number > ancestor {
+				delete(w.redo, number)
+			}
+		}
+	}
+	d.mu.Unlock()
+
+	d.publish(func(sink Sink) error { return sink.OnReorg(ancestor, tip, retracted) })
+}
+
+// OnError publishes a processing error
+func (d *Dispatcher) OnError(err error, context string) {
+	d.publish(func(sink Sink) error { return sink.OnError(err, context) })
+}
+
+// Flush waits until every durable sink has handled the events published so
+// far and returns their failures since the last Flush. Blocks must not be
+// treated as done, e.g. checkpointed, until it returns nil. A failed range is
+// published again and then only goes to the durable sinks that failed it, so
+// those see events at least once and the other sinks don't see them twice.
+func (d *Dispatcher) Flush() error {
+	var errs []error
+	for _, w := range d.workers {
+		if !w.durable {
+			continue
+		}
+
+		done := make(chan struct{})
+		w.events <- func(Sink) error {
+			close(done)
+			return nil
+		}
+		<-done
+
+		w.mu.Lock()
+		failed := w.failed > 0
+		if failed {
+			errs = append(errs, fmt.Errorf("%s sink failed %d events: %w", w.sink.Name(), w.failed, w.firstErr))
+		}
+		w.failed, w.firstErr = 0, nil
+		w.mu.Unlock()
+
+		d.mu.Lock()
+		if failed {
+			for _, number := range w.published {
+				w.redo[number] = true
+				d.retrying[number] = true
+			}
+		}
+		w.published = nil
+		d.mu.Unlock()
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close waits for every queued event to be delivered and closes the sinks
+func (d *Dispatcher) Close() {
+	for _, w := range d.workers {
+		close(w.events)
+	}
+	d.wg.Wait()
+
+	for _, w := range d.workers {
+		if err := w.sink.Close(); err != nil {
+			log.Error().Err(err).Str("sink", w.sink.Name()).Msg("Failed to close output sink")
+		}
+	}
+}
+
+// publish queues an event for every sink
+func (d *Dispatcher) publish(event func(Sink) error) {
+	d.send(d.workers, event)
+}
+
+// publishBlock queues an event of a block. A block a durable sink failed only
+// goes to the sinks that failed it; its OnBlock event, the last one, ends
+// the retry.
+func (d *Dispatcher) publishBlock(number uint64, last bool, event func(Sink) error) {
+	d.mu.Lock()
+	workers := d.workers
+	if d.retrying[number] {
+		workers = nil
+		for _, w := range d.workers {
+			if w.redo[number] {
+				workers = append(workers, w)
+			}
+		}
+	}
+	if last {
+		delete(d.retrying, number)
+		for _, w := range workers {
+			delete(w.redo, number)
+			if w.durable {
+				w.published = append(w.published, number)
+			}
+		}
+	}
+	d.mu.Unlock()
+
+	d.send(workers, event)
+}
+
+// send queues an event for the given sinks. The event is dropped for
+// non-durable sinks whose queue is full.
+func (d *Dispatcher) send(workers []*sinkWorker, event func(Sink) error) {
+	for _, w := range workers {
+		if w.durable {
+			w.events <- event
+			continue
+		}
+
+		select {
+		case w.events <- event:
+		default:
+			dropped := w.dropped.Add(1)
+			// Warn on the first drop and then periodically to avoid flooding
+			if dropped%1000 == 1 {
+				log.Warn().
+					Str("sink", w.sink.Name()).
+					Uint64("dropped", dropped).
+					Msg("Output sink queue full, dropping events")
+			}
+		}
+	}
+}
+
+// run delivers events until the queue is closed
+func (w *sinkWorker) run() {
+	for event := range w.events {
+		w.deliver(event)
+	}
+}
+
+// deliver hands one event to the sink, isolating its errors and panics
+func (w *sinkWorker) deliver(event func(Sink) error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().
+				Str("sink", w.sink.Name()).
+				Str("panic", fmt.Sprint(r)).
+				Msg("Output sink panicked")
+			w.fail(fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	if err := event(w.sink); err != nil {
+		log.Error().Err(err).Str("sink", w.sink.Name()).Msg("Output sink failed")
+		w.fail(err)
+	}
+}
+
+// fail records a failure of a durable sink for the next Flush
+func (w *sinkWorker) fail(err error) {
+	if !w.durable {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failed == 0 {
+		w.firstErr = err
+	}
+	w.failed++
+}
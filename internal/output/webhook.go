@@ -0,0 +1,99 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+// WebhookSink POSTs arbitrage, block, reorg and error events as JSON to an
+// HTTP endpoint. Swaps are not sent to keep request volume manageable; they
+// are included in the block event.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink creates a sink posting to url
+func NewWebhookSink(url string, timeout time.Duration) *WebhookSink {
+	return &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name returns the sink name
+func (w *WebhookSink) Name() string {
+	return "webhook"
+}
+
+// Durable implements Durable: events are never dropped for the webhook
+func (w *WebhookSink) Durable() bool {
+	return true
+}
+
+// OnSwap implements Sink; swaps are delivered with their block
+func (w *WebhookSink) OnSwap(swap *types.Swap) error {
+	return nil
+}
+
+// OnArbitrage posts an "arbitrage" event
+func (w *WebhookSink) OnArbitrage(arb *types.Arbitrage) error {
+	return w.post("arbitrage", arb)
+}
+
+// OnBlock posts a "block" event
+func (w *WebhookSink) OnBlock(block *types.BlockResult) error {
+	return w.post("block", block)
+}
+
+// OnReorg posts a "reorg" event
+func (w *WebhookSink) OnReorg(ancestor, tip uint64, retracted []*types.BlockResult) error {
+	return w.post("reorg", ReorgEvent{
+		CommonAncestor: ancestor,
+		ReplacedTo:     tip,
+		Retracted:      retracted,
+	})
+}
+
+// OnError posts an "error" event
+func (w *WebhookSink) OnError(err error, context string) error {
+	return w.post("error", ErrorEvent{
+		Context: context,
+		Error:   err.Error(),
+	})
+}
+
+// Close implements Sink
+func (w *WebhookSink) Close() error {
+	w.client.CloseIdleConnections()
+	return nil
+}
+
+// post sends one event
+func (w *WebhookSink) post(eventType string, data interface{}) error {
+	body, err := json.Marshal(Event{
+		Type: eventType,
+		Time: time.Now().UTC(),
+		Data: data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to post %s event: %w", eventType, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s for %s event", resp.Status, eventType)
+	}
+
+	return nil
+}
@@ -0,0 +1,251 @@
+// Package pricing values token amounts in ETH and USD using the exchange
+// rates implied by the swaps decoded in a block, so no price feed is needed.
+package pricing
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/devlongs/mev-inspector/internal/arbitrage"
+	"github.com/devlongs/mev-inspector/internal/config"
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+// Longest chain of pairs followed to price a token, e.g. X -> USDC -> WETH
+const maxHops = 3
+
+// Anchor is a stablecoin taken to be worth one US dollar
+type Anchor struct {
+	Token    common.Address
+	Decimals uint8
+}
+
+// Pricer values amounts against WETH and a list of USD anchors
+type Pricer struct {
+	weth    common.Address
+	anchors []Anchor
+}
+
+// NewPricer creates a pricer from the pricing configuration
+func NewPricer(cfg config.PricingConfig) (*Pricer, error) {
+	if !common.IsHexAddress(cfg.WETH) {
+		return nil, fmt.Errorf("invalid pricing.weth address %q", cfg.WETH)
+	}
+
+	p := &Pricer{weth: common.HexToAddress(cfg.WETH)}
+	for _, sc := range cfg.Stablecoins {
+		if !common.IsHexAddress(sc.Address) {
+			return nil, fmt.Errorf("invalid pricing.stablecoins address %q", sc.Address)
+		}
+		p.anchors = append(p.anchors, Anchor{
+			Token:    common.HexToAddress(sc.Address),
+			Decimals: sc.Decimals,
+		})
+	}
+
+	return p, nil
+}
+
+// Book holds the exchange rates implied by the swaps of one block. The rate
+// of a pair is weighted by volume over every swap between its tokens, so a
+// single small or skewed trade moves it little.
+type Book struct {
+	// volume[a][b] is the total amount of a traded against b
+	volume map[common.Address]map[common.Address]*big.Int
+}
+
+// NewBook builds the book of a block from its swaps
+func NewBook(swaps []types.Swap) *Book {
+	b := &Book{volume: make(map[common.Address]map[common.Address]*big.Int)}
+	for idx := range swaps {
+		flow, ok := arbitrage.FlowOf(&swaps[idx])
+		if !ok || flow.TokenIn == flow.TokenOut || flow.AmountIn.Sign() <= 0 || flow.AmountOut.Sign() <= 0 {
+			continue
+		}
+		b.add(flow.TokenIn, flow.TokenOut, flow.AmountIn)
+		b.add(flow.TokenOut, flow.TokenIn, flow.AmountOut)
+	}
+	return b
+}
+
+func (b *Book) add(token, against common.Address, amount *big.Int) {
+	pairs, ok := b.volume[token]
+	if !ok {
+		pairs = make(map[common.Address]*big.Int)
+		b.volume[token] = pairs
+	}
+	if v, ok := pairs[against]; ok {
+		v.Add(v, amount)
+	} else {
+		pairs[against] = new(big.Int).Set(amount)
+	}
+}
+
+// Convert returns what amount of from is worth in to. ok is false when no
+// chain of at most maxHops traded pairs connects the tokens.
+func (b *Book) Convert(from, to common.Address, amount *big.Int) (*big.Int, bool) {
+	rate, ok := b.Rate(from, to)
+	if !ok {
+		return nil, false
+	}
+	value := rate.Mul(rate, new(big.Rat).SetInt(amount))
+	return new(big.Int).Quo(value.Num(), value.Denom()), true
+}
+
+// Rate returns the amount of to one unit of from is worth, in the smallest
+// units of both, following the shortest chain of traded pairs and preferring
+// the most traded ones
+func (b *Book) Rate(from, to common.Address) (*big.Rat, bool) {
+	if from == to {
+		return big.NewRat(1, 1), true
+	}
+
+	path := b.path(from, to)
+	if path == nil {
+		return nil, false
+	}
+
+	rate := big.NewRat(1, 1)
+	for idx := 1; idx < len(path); idx++ {
+		in, out := path[idx-1], path[idx]
+		rate.Mul(rate, new(big.Rat).SetFrac(b.volume[out][in], b.volume[in][out]))
+	}
+	return rate, true
+}
+
+// path finds the tokens to convert through with a breadth-first search
+func (b *Book) path(from, to common.Address) []common.Address {
+	prev := map[common.Address]common.Address{from: {}}
+	frontier := []common.Address{from}
+
+	for hop := 0; hop < maxHops && len(frontier) > 0; hop++ {
+		var next []common.Address
+		for _, token := range frontier {
+			for _, neighbour := range b.neighbours(token) {
+				if _, seen := prev[neighbour]; seen {
+					continue
+				}
+				prev[neighbour] = token
+				if neighbour == to {
+					path := []common.Address{to}
+					for t := token; t != from; t = prev[t] {
+						path = append(path, t)
+					}
+					path = append(path, from)
+					for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+						path[i], path[j] = path[j], path[i]
+					}
+					return path
+				}
+				next = append(next, neighbour)
+			}
+		}
+		frontier = next
+	}
+
+	return nil
+}
+
+// neighbours lists the tokens traded against token, most traded first
+func (b *Book) neighbours(token common.Address) []common.Address {
+	pairs := b.volume[token]
+	out := make([]common.Address, 0, len(pairs))
+	for t := range pairs {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if c := pairs[out[i]].Cmp(pairs[out[j]]); c != 0 {
+			return c > 0
+		}
+		return bytes.Compare(out[i][:], out[j][:]) < 0
+	})
+	return out
+}
+
+// ToETH values an amount of token in wei
+func (p *Pricer) ToETH(book *Book, token common.Address, amount *big.Int) (*big.Int, bool) {
+	return book.Convert(token, p.weth, amount)
+}
+
+// ToUSD values an amount of token in US dollars. Anchors are taken at face
+// value; other tokens are priced through WETH if possible, and otherwise
+// directly against an anchor.
+func (p *Pricer) ToUSD(book *Book, token common.Address, amount *big.Int) (float64, bool) {
+	for _, anchor := range p.anchors {
+		if token == anchor.Token {
+			return usd(amount, big.NewRat(1, 1), anchor.Decimals), true
+		}
+	}
+
+	if toETH, ok := book.Rate(token, p.weth); ok {
+		for _, anchor := range p.anchors {
+			if toUSD, ok := book.Rate(p.weth, anchor.Token); ok {
+				return usd(amount, toETH.Mul(toETH, toUSD), anchor.Decimals), true
+			}
+		}
+	}
+
+	for _, anchor := range p.anchors {
+		if rate, ok := book.Rate(token, anchor.Token); ok {
+			return usd(amount, rate, anchor.Decimals), true
+		}
+	}
+
+	return 0, false
+}
+
+// Value fills in the ETH and USD value of an arbitrage's profit and, when
+// the detector couldn't, its net profit in ETH. Flash loan fees paid in
+// other tokens are converted to the profit token and added to
+// FlashLoanFees; if one can't be priced the fees and net profit are unknown
+// and left nil.
+func (p *Pricer) Value(book *Book, arb *types.Arbitrage) {
+	if arb.Profit == nil {
+		return
+	}
+
+	if wei, ok := p.ToETH(book, arb.ProfitToken, arb.Profit); ok {
+		arb.ProfitETH = wei
+	}
+	if dollars, ok := p.ToUSD(book, arb.ProfitToken, arb.Profit); ok {
+		arb.ProfitUSD = &dollars
+	}
+
+	if arb.NetProfitWei != nil || arb.GasPrice == nil {
+		return
+	}
+
+	fees := new(big.Int)
+	if arb.FlashLoanFees != nil {
+		fees.Set(arb.FlashLoanFees)
+	}
+	for _, loan := range arb.FlashLoans {
+		if loan.Token == arb.ProfitToken || loan.Fee == nil || loan.Fee.Sign() == 0 {
+			continue
+		}
+		converted, ok := book.Convert(loan.Token, arb.ProfitToken, loan.Fee)
+		if !ok {
+			arb.FlashLoanFees = nil
+			return
+		}
+		fees.Add(fees, converted)
+	}
+	arb.FlashLoanFees = fees
+
+	net := new(big.Int).Sub(arb.Profit, fees)
+	if wei, ok := p.ToETH(book, arb.ProfitToken, net); ok {
+		arb.NetProfitWei = wei.Sub(wei, arbitrage.GasCost(arb))
+	}
+}
+
+// usd converts an amount to whole units of an anchor at rate
+func usd(amount *big.Int, rate *big.Rat, decimals uint8) float64 {
+	value := new(big.Rat).Mul(new(big.Rat).SetInt(amount), rate)
+	value.Quo(value, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)))
+	f, _ := value.Float64()
+	return f
+}
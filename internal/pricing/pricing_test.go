@@ -0,0 +1,158 @@
+package pricing
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/devlongs/mev-inspector/internal/config"
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+var (
+	weth = common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	usdc = common.HexToAddress("0xA0b86991c6218b36c1D19D4a2e9Eb0cE3606eB48")
+	pepe = common.HexToAddress("0x6982508145454Ce325dDbE47a25d4ec3d2311933")
+	lone = common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+)
+
+func amount(s string) *big.Int {
+	v, _ := new(big.Int).SetString(s, 10)
+	return v
+}
+
+// swap sells amountIn of tokenIn for amountOut of tokenOut
+func swap(tokenIn, tokenOut common.Address, amountIn, amountOut string) types.Swap {
+	return types.Swap{
+		Token0:     tokenIn,
+		Token1:     tokenOut,
+		Amount0In:  amount(amountIn),
+		Amount1In:  new(big.Int),
+		Amount0Out: new(big.Int),
+		Amount1Out: amount(amountOut),
+	}
+}
+
+func newTestPricer(t *testing.T) *Pricer {
+	t.Helper()
+	p, err := NewPricer(config.PricingConfig{
+		WETH:        weth.Hex(),
+		Stablecoins: []config.StablecoinConfig{{Address: usdc.Hex(), Decimals: 6}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestValue(t *testing.T) {
+	// 2,000 USDC per WETH, volume weighted over both directions, and 1 PEPE
+	// for 0.5 USDC
+	book := NewBook([]types.Swap{
+		swap(weth, usdc, "1000000000000000000", "1990000000"),
+		swap(usdc, weth, "2010000000", "1000000000000000000"),
+		swap(pepe, usdc, "100000000000000000000", "50000000"),
+	})
+	p := newTestPricer(t)
+
+	tests := []struct {
+		name    string
+		arb     types.Arbitrage
+		wantETH string
+		wantUSD float64
+		wantNet string
+	}{
+		{
+			name: "stablecoin profit",
+			arb: types.Arbitrage{
+				ProfitToken: usdc,
+				Profit:      amount("100000000"),
+				GasUsed:     100000,
+				GasPrice:    amount("10000000000"),
+			},
+			wantETH: "50000000000000000",
+			wantUSD: 100,
+			wantNet: "49000000000000000",
+		},
+		{
+			name: "two hops to WETH",
+			arb: types.Arbitrage{
+				ProfitToken:   pepe,
+				Profit:        amount("10000000000000000000"),
+				FlashLoanFees: amount("2000000000000000000"),
+				GasUsed:       100000,
+				GasPrice:      amount("1000000000"),
+			},
+			wantETH: "2500000000000000",
+			wantUSD: 5,
+			wantNet: "1900000000000000",
+		},
+		{
+			name: "WETH profit with a flash loan fee in USDC",
+			arb: types.Arbitrage{
+				ProfitToken: weth,
+				Profit:      amount("100000000000000000"),
+				FlashLoans: []types.FlashLoan{
+					{Token: usdc, Amount: amount("1000000000000"), Fee: amount("20000000")},
+				},
+				FlashLoanFees: new(big.Int),
+				GasUsed:       100000,
+				GasPrice:      amount("1000000000"),
+			},
+			wantETH: "100000000000000000",
+			wantUSD: 200,
+			wantNet: "89900000000000000",
+		},
+		{
+			name: "flash loan fee in an unpriced token",
+			arb: types.Arbitrage{
+				ProfitToken: weth,
+				Profit:      amount("100000000000000000"),
+				FlashLoans: []types.FlashLoan{
+					{Token: lone, Amount: amount("1000000"), Fee: amount("1000")},
+				},
+				FlashLoanFees: new(big.Int),
+				GasUsed:       100000,
+				GasPrice:      amount("1000000000"),
+			},
+			wantETH: "100000000000000000",
+			wantUSD: 200,
+		},
+		{
+			name: "unpriced token",
+			arb: types.Arbitrage{
+				ProfitToken: lone,
+				Profit:      amount("1000"),
+				GasUsed:     100000,
+				GasPrice:    amount("1000000000"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		arb := tt.arb
+		p.Value(book, &arb)
+
+		if got := bigString(arb.ProfitETH); got != tt.wantETH {
+			t.Errorf("%s: ProfitETH = %s, want %s", tt.name, got, tt.wantETH)
+		}
+		if got := bigString(arb.NetProfitWei); got != tt.wantNet {
+			t.Errorf("%s: NetProfitWei = %s, want %s", tt.name, got, tt.wantNet)
+		}
+		switch {
+		case tt.wantETH == "" && arb.ProfitUSD != nil:
+			t.Errorf("%s: ProfitUSD = %f, want nil", tt.name, *arb.ProfitUSD)
+		case tt.wantETH != "" && (arb.ProfitUSD == nil || math.Abs(*arb.ProfitUSD-tt.wantUSD) > 1e-9):
+			t.Errorf("%s: ProfitUSD = %v, want %f", tt.name, arb.ProfitUSD, tt.wantUSD)
+		}
+	}
+}
+
+func bigString(v *big.Int) string {
+	if v == nil {
+		return ""
+	}
+	return v.String()
+}
@@ -0,0 +1,147 @@
+package reorg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/devlongs/mev-inspector/internal/eth"
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+// ErrTooDeep is returned when no tracked block is still canonical
+var ErrTooDeep = errors.New("reorg deeper than tracked blocks")
+
+// HeaderSource fetches canonical block headers
+type HeaderSource interface {
+	BlockHeaderByNumber(ctx context.Context, number uint64) (*eth.BlockHeader, error)
+}
+
+// Tracker remembers the hashes and results of recently processed blocks so
+// that blocks replaced by a reorg can be detected and their results retracted
+type Tracker struct {
+	depth  uint64
+	blocks map[uint64]*types.BlockResult
+	mu     sync.Mutex
+}
+
+// NewTracker creates a tracker that keeps the last depth blocks
+func NewTracker(depth uint64) *Tracker {
+	return &Tracker{
+		depth:  depth,
+		blocks: make(map[uint64]*types.BlockResult),
+	}
+}
+
+// Record stores a processed block and prunes blocks beyond the tracked depth
+func (t *Tracker) Record(block *types.BlockResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.blocks[block.Number] = block
+
+	if block.Number >= t.depth {
+		for number := range t.blocks {
+			if number <= block.Number-t.depth {
+				delete(t.blocks, number)
+			}
+		}
+	}
+}
+
+// Hash returns the recorded hash of a block
+func (t *Tracker) Hash(number uint64) (common.Hash, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	block, ok := t.blocks[number]
+	if !ok {
+		return common.Hash{}, false
+	}
+	return block.Hash, true
+}
+
+// ExtendsChain reports whether header builds on the recorded parent block.
+// Headers whose parent isn't tracked are assumed to extend the chain.
+func (t *Tracker) ExtendsChain(header *ethtypes.Header) bool {
+	if header.Number.Uint64() == 0 {
+		return true
+	}
+
+	parentHash, ok := t.Hash(header.Number.Uint64() - 1)
+	if !ok {
+		return true
+	}
+	return parentHash == header.ParentHash
+}
+
+// FindCommonAncestor walks back from tip comparing recorded hashes against
+// the canonical chain and returns the highest block that is still canonical.
+// If no tracked block matches, the block below the oldest tracked one is
+// returned along with ErrTooDeep.
+func (t *Tracker) FindCommonAncestor(ctx context.Context, source HeaderSource, tip uint64) (uint64, error) {
+	numbers := t.trackedNumbers()
+
+	for idx := len(numbers) - 1; idx >= 0; idx-- {
+		number := numbers[idx]
+		if number > tip {
+			continue
+		}
+
+		header, err := source.BlockHeaderByNumber(ctx, number)
+		if err != nil {
+			return 0, err
+		}
+
+		if hash, _ := t.Hash(number); hash == header.Hash {
+			return number, nil
+		}
+	}
+
+	if len(numbers) == 0 || numbers[0] == 0 {
+		return 0, fmt.Errorf("%w (depth %d)", ErrTooDeep, t.depth)
+	}
+	return numbers[0] - 1, fmt.Errorf("%w (depth %d)", ErrTooDeep, t.depth)
+}
+
+// Rollback removes and returns all recorded blocks from fromBlock onwards,
+// ordered by block number
+func (t *Tracker) Rollback(fromBlock uint64) []*types.BlockResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var removed []*types.BlockResult
+	for number, block := range t.blocks {
+		if number >= fromBlock {
+			removed = append(removed, block)
+			delete(t.blocks, number)
+		}
+	}
+
+	sort.Slice(removed, func(i, j int) bool {
+		return removed[i].Number < removed[j].Number
+	})
+
+	return removed
+}
+
+// trackedNumbers returns the recorded block numbers in ascending order
+func (t *Tracker) trackedNumbers() []uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	numbers := make([]uint64, 0, len(t.blocks))
+	for number := range t.blocks {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool {
+		return numbers[i] < numbers[j]
+	})
+
+	return numbers
+}
@@ -0,0 +1,181 @@
+package sandwich
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+
+	"github.com/devlongs/mev-inspector/internal/arbitrage"
+	"github.com/devlongs/mev-inspector/internal/dex/uniswapv4"
+	"github.com/devlongs/mev-inspector/internal/eth"
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+// Detector detects sandwich attacks across transactions in a block
+type Detector struct {
+	client eth.Reader
+}
+
+// NewDetector creates a new sandwich detector. The client looks up the
+// sender of transactions whose swaps don't name the trader, such as
+// Balancer Vault swaps.
+func NewDetector(client eth.Reader) *Detector {
+	return &Detector{client: client}
+}
+
+// DetectSandwiches analyzes the block-ordered swap stream of a block range
+// and returns every frontrun/victim/backrun pattern found on a single pool
+func (d *Detector) DetectSandwiches(ctx context.Context, swaps []types.Swap) []types.Sandwich {
+	if len(swaps) < 3 {
+		return nil
+	}
+
+	// Sort swaps by block and log index to maintain execution order
+	ordered := make([]types.Swap, len(swaps))
+	copy(ordered, swaps)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].BlockNumber != ordered[j].BlockNumber {
+			return ordered[i].BlockNumber < ordered[j].BlockNumber
+		}
+		return ordered[i].LogIndex < ordered[j].LogIndex
+	})
+
+	var sandwiches []types.Sandwich
+
+	// Swaps already consumed as a backrun can't start another sandwich
+	usedBackruns := make(map[int]bool)
+	senders := make(map[common.Hash]common.Address)
+
+	for i := range ordered {
+		if usedBackruns[i] {
+			continue
+		}
+		if sandwich := d.findSandwich(ctx, ordered, i, usedBackruns, senders); sandwich != nil {
+			sandwiches = append(sandwiches, *sandwich)
+		}
+	}
+
+	return sandwiches
+}
+
+// findSandwich treats ordered[start] as a frontrun and scans forward in the
+// same block for victims and a matching backrun on the same pool
+func (d *Detector) findSandwich(ctx context.Context, ordered []types.Swap, start int, usedBackruns map[int]bool, senders map[common.Hash]common.Address) *types.Sandwich {
+	front := ordered[start]
+	frontFlow, ok := arbitrage.FlowOf(&front)
+	if !ok {
+		return nil
+	}
+
+	// The attacker contract receives the frontrun output and spends it in
+	// the backrun. Swaps that don't name the trader are attributed to the
+	// sender of their transaction instead.
+	attacker := front.Recipient
+	if !namesTrader(front) {
+		attacker = d.txSender(ctx, front.TxHash, senders)
+	}
+	if attacker == (common.Address{}) {
+		return nil
+	}
+
+	var victims []types.Swap
+
+	for j := start + 1; j < len(ordered); j++ {
+		swap := ordered[j]
+		if swap.BlockNumber != front.BlockNumber {
+			break
+		}
+		if swap.Pool != front.Pool || swap.PoolID != front.PoolID || swap.TxHash == front.TxHash {
+			continue
+		}
+
+		flow, ok := arbitrage.FlowOf(&swap)
+		if !ok {
+			continue
+		}
+
+		var isAttacker bool
+		if namesTrader(swap) {
+			isAttacker = swap.Sender == attacker || swap.Recipient == attacker
+		} else {
+			isAttacker = d.txSender(ctx, swap.TxHash, senders) == attacker
+		}
+
+		switch {
+		case isAttacker && flow.TokenIn == frontFlow.TokenOut && flow.TokenOut == frontFlow.TokenIn:
+			// Backrun: attacker sells back what the frontrun bought. Swaps in
+			// the backrun transaction itself are the attacker's, not victims.
+			kept := victims[:0]
+			for _, victim := range victims {
+				if victim.TxHash != swap.TxHash {
+					kept = append(kept, victim)
+				}
+			}
+			if len(kept) == 0 {
+				return nil
+			}
+			usedBackruns[j] = true
+			return buildSandwich(front, frontFlow, swap, flow, kept, attacker)
+
+		case !isAttacker && flow.TokenIn == frontFlow.TokenIn && flow.TokenOut == frontFlow.TokenOut:
+			// Victim: trades in the same direction as the frontrun
+			if len(victims) > 0 && victims[len(victims)-1].TxHash == swap.TxHash {
+				continue
+			}
+			victims = append(victims, swap)
+		}
+	}
+
+	return nil
+}
+
+// buildSandwich assembles a Sandwich result and computes the attacker's
+// profit. VictimLoss is left unset: what the victims would have received
+// without the frontrun depends on pool state from before it, which the swap
+// stream doesn't carry.
+func buildSandwich(front types.Swap, frontFlow arbitrage.TokenFlow, back types.Swap, backFlow arbitrage.TokenFlow, victims []types.Swap, attacker common.Address) *types.Sandwich {
+	return &types.Sandwich{
+		BlockNumber: front.BlockNumber,
+		Attacker:    attacker,
+		Pool:        front.Pool,
+		PoolID:      front.PoolID,
+		Frontrun:    front,
+		Victims:     victims,
+		Backrun:     back,
+		ProfitToken: frontFlow.TokenIn,
+		Profit:      new(big.Int).Sub(backFlow.AmountOut, frontFlow.AmountIn),
+	}
+}
+
+// namesTrader reports whether a swap names its trader. Balancer Vault and
+// some other events only carry the pool and tokens, and the sender of a
+// Uniswap V4 swap is the router that called the PoolManager.
+func namesTrader(swap types.Swap) bool {
+	if swap.Protocol == uniswapv4.ProtocolName {
+		return false
+	}
+	return swap.Sender != (common.Address{}) || swap.Recipient != (common.Address{})
+}
+
+// txSender returns the sender of a transaction, remembering it for the rest
+// of the scan. It returns the zero address if the lookup fails.
+func (d *Detector) txSender(ctx context.Context, txHash common.Hash, senders map[common.Hash]common.Address) common.Address {
+	if from, ok := senders[txHash]; ok {
+		return from
+	}
+
+	var from common.Address
+	tx, _, err := d.client.GetTransaction(ctx, txHash)
+	if err != nil {
+		log.Debug().Err(err).Str("tx", txHash.Hex()).Msg("Failed to fetch transaction sender")
+	} else if sender, err := ethtypes.Sender(ethtypes.LatestSignerForChainID(d.client.ChainID()), tx); err == nil {
+		from = sender
+	}
+
+	senders[txHash] = from
+	return from
+}
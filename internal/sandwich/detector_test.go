@@ -0,0 +1,222 @@
+package sandwich
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/devlongs/mev-inspector/internal/dex/uniswapv4"
+	"github.com/devlongs/mev-inspector/internal/eth"
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+var (
+	weth     = common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	usdc     = common.HexToAddress("0xA0b86991c6218b36c1D19D4a2e9Eb0cE3606eB48")
+	pool     = common.HexToAddress("0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc")
+	bot      = common.HexToAddress("0x00000000000000000000000000000000000B0700")
+	router   = common.HexToAddress("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D")
+	balancer = common.HexToAddress("0xBA12222222228d8Ba445958a75a0704d566BF2C8")
+)
+
+// fakeReader serves transactions signed by known keys, for swaps that are
+// attributed to their transaction's sender
+type fakeReader struct {
+	eth.Reader
+	txs map[common.Hash]*ethtypes.Transaction
+}
+
+func (f *fakeReader) ChainID() *big.Int { return big.NewInt(1) }
+
+func (f *fakeReader) GetTransaction(_ context.Context, hash common.Hash) (*ethtypes.Transaction, bool, error) {
+	tx, ok := f.txs[hash]
+	if !ok {
+		return nil, false, fmt.Errorf("transaction %s not found", hash.Hex())
+	}
+	return tx, false, nil
+}
+
+// sign adds a transaction from key to the reader and returns its hash
+func (f *fakeReader) sign(t *testing.T, key *ecdsa.PrivateKey, nonce uint64) common.Hash {
+	t.Helper()
+	tx, err := ethtypes.SignTx(
+		ethtypes.NewTx(&ethtypes.LegacyTx{Nonce: nonce, Gas: 21000, GasPrice: big.NewInt(1)}),
+		ethtypes.LatestSignerForChainID(f.ChainID()),
+		key,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.txs[tx.Hash()] = tx
+	return tx.Hash()
+}
+
+// swap sells amountIn of tokenIn for amountOut of tokenOut on pool
+func swap(tx common.Hash, logIndex uint, tokenIn, tokenOut common.Address, amountIn, amountOut int64, sender, recipient common.Address) types.Swap {
+	s := types.Swap{
+		TxHash:      tx,
+		BlockNumber: 100,
+		LogIndex:    logIndex,
+		Pool:        pool,
+		Sender:      sender,
+		Recipient:   recipient,
+		Token0:      weth,
+		Token1:      usdc,
+		Amount0In:   new(big.Int),
+		Amount1In:   new(big.Int),
+		Amount0Out:  new(big.Int),
+		Amount1Out:  new(big.Int),
+	}
+	if tokenIn == weth {
+		s.Amount0In.SetInt64(amountIn)
+		s.Amount1Out.SetInt64(amountOut)
+	} else {
+		s.Amount1In.SetInt64(amountIn)
+		s.Amount0Out.SetInt64(amountOut)
+	}
+	return s
+}
+
+func hash(n byte) common.Hash {
+	return common.BytesToHash([]byte{n})
+}
+
+func TestDetectSandwiches(t *testing.T) {
+	tests := []struct {
+		name    string
+		swaps   []types.Swap
+		victims []common.Hash
+		profit  int64
+	}{
+		{
+			name: "frontrun, victim, backrun",
+			swaps: []types.Swap{
+				swap(hash(1), 0, weth, usdc, 1000, 2000, bot, bot),
+				swap(hash(2), 1, weth, usdc, 500, 900, router, router),
+				swap(hash(3), 2, usdc, weth, 2000, 1050, bot, bot),
+			},
+			victims: []common.Hash{hash(2)},
+			profit:  50,
+		},
+		{
+			name: "only same-direction swap is in the backrun transaction",
+			swaps: []types.Swap{
+				swap(hash(1), 0, weth, usdc, 1000, 2000, bot, bot),
+				swap(hash(3), 1, weth, usdc, 500, 900, router, router),
+				swap(hash(3), 2, usdc, weth, 2000, 1050, bot, bot),
+			},
+		},
+		{
+			name: "swap in the backrun transaction is not a victim",
+			swaps: []types.Swap{
+				swap(hash(1), 0, weth, usdc, 1000, 2000, bot, bot),
+				swap(hash(2), 1, weth, usdc, 500, 900, router, router),
+				swap(hash(3), 2, weth, usdc, 10, 18, router, router),
+				swap(hash(3), 3, usdc, weth, 2000, 1050, bot, bot),
+			},
+			victims: []common.Hash{hash(2)},
+			profit:  50,
+		},
+		{
+			name: "no victim between the attacker's swaps",
+			swaps: []types.Swap{
+				swap(hash(1), 0, weth, usdc, 1000, 2000, bot, bot),
+				swap(hash(2), 1, usdc, weth, 2000, 1050, bot, bot),
+				swap(hash(3), 2, weth, usdc, 500, 900, router, router),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDetector(&fakeReader{})
+			got := d.DetectSandwiches(context.Background(), tt.swaps)
+			if tt.victims == nil {
+				if len(got) != 0 {
+					t.Fatalf("got %d sandwiches, want none", len(got))
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("got %d sandwiches, want 1", len(got))
+			}
+			checkSandwich(t, got[0], bot, tt.victims, tt.profit)
+		})
+	}
+}
+
+func TestDetectSandwichesFromTxSender(t *testing.T) {
+	tests := []struct {
+		name     string
+		pool     common.Address
+		protocol string
+		party    common.Address // Sender and recipient of every swap
+	}{
+		// Balancer Vault swaps carry no sender or recipient
+		{name: "balancer", pool: balancer, protocol: "balancer_v2"},
+		// Uniswap V4 swaps name the router that called the PoolManager
+		{name: "uniswap v4", pool: uniswapv4.PoolManager, protocol: uniswapv4.ProtocolName, party: router},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attackerKey, _ := crypto.GenerateKey()
+			victimKey, _ := crypto.GenerateKey()
+			reader := &fakeReader{txs: make(map[common.Hash]*ethtypes.Transaction)}
+			front := reader.sign(t, attackerKey, 0)
+			victim := reader.sign(t, victimKey, 0)
+			back := reader.sign(t, attackerKey, 1)
+
+			swaps := []types.Swap{
+				swap(front, 0, weth, usdc, 1000, 2000, tt.party, tt.party),
+				swap(victim, 1, weth, usdc, 500, 900, tt.party, tt.party),
+				swap(back, 2, usdc, weth, 2000, 1050, tt.party, tt.party),
+			}
+			// A swap on another pool of the same singleton is not a victim
+			other := swap(hash(4), 1, weth, usdc, 300, 540, common.Address{}, common.Address{})
+			other.Pool, other.PoolID, other.Protocol = tt.pool, hash(0xbb), tt.protocol
+			swaps = append(swaps, other)
+			for idx := range swaps[:3] {
+				swaps[idx].Pool = tt.pool
+				swaps[idx].PoolID = hash(0xba)
+				swaps[idx].Protocol = tt.protocol
+			}
+
+			got := NewDetector(reader).DetectSandwiches(context.Background(), swaps)
+			if len(got) != 1 {
+				t.Fatalf("got %d sandwiches, want 1", len(got))
+			}
+			checkSandwich(t, got[0], crypto.PubkeyToAddress(attackerKey.PublicKey), []common.Hash{victim}, 50)
+			if got[0].Pool != tt.pool || got[0].PoolID != hash(0xba) {
+				t.Errorf("pool = %s %s, want %s %s", got[0].Pool.Hex(), got[0].PoolID.Hex(), tt.pool.Hex(), hash(0xba).Hex())
+			}
+		})
+	}
+}
+
+func checkSandwich(t *testing.T, got types.Sandwich, attacker common.Address, victims []common.Hash, profit int64) {
+	t.Helper()
+	if got.Attacker != attacker {
+		t.Errorf("attacker = %s, want %s", got.Attacker.Hex(), attacker.Hex())
+	}
+	if len(got.Victims) != len(victims) {
+		t.Fatalf("got %d victims, want %d", len(got.Victims), len(victims))
+	}
+	for idx, victim := range got.Victims {
+		if victim.TxHash != victims[idx] {
+			t.Errorf("victim %d = %s, want %s", idx, victim.TxHash.Hex(), victims[idx].Hex())
+		}
+	}
+	if got.ProfitToken != weth || got.Profit.Int64() != profit {
+		t.Errorf("profit = %s in %s, want %d WETH", got.Profit, got.ProfitToken.Hex(), profit)
+	}
+	if got.VictimLoss != nil {
+		t.Errorf("victim loss = %s, want unknown", got.VictimLoss)
+	}
+}
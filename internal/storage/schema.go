@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// schema creates every table used by the store. Token amounts are stored as
+// decimal strings in a column of type {{amount}} so they keep full uint256
+// precision on both backends.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS blocks (
+		number          BIGINT PRIMARY KEY,
+		hash            TEXT NOT NULL,
+		parent_hash     TEXT NOT NULL,
+		swap_count      INTEGER NOT NULL,
+		arbitrage_count INTEGER NOT NULL,
+		processing_ms   BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS pools (
+		address          TEXT NOT NULL,
+		pool_id          TEXT NOT NULL,
+		protocol         TEXT NOT NULL,
+		token0           TEXT NOT NULL,
+		token1           TEXT NOT NULL,
+		first_seen_block BIGINT NOT NULL,
+		PRIMARY KEY (address, pool_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS swaps (
+		tx_hash      TEXT NOT NULL,
+		log_index    BIGINT NOT NULL,
+		block_number BIGINT NOT NULL,
+		protocol     TEXT NOT NULL,
+		pool         TEXT NOT NULL,
+		pool_id      TEXT NOT NULL,
+		sender       TEXT NOT NULL,
+		recipient    TEXT NOT NULL,
+		token0       TEXT NOT NULL,
+		token1       TEXT NOT NULL,
+		amount0_in   {{amount}} NOT NULL,
+		amount1_in   {{amount}} NOT NULL,
+		amount0_out  {{amount}} NOT NULL,
+		amount1_out  {{amount}} NOT NULL,
+		PRIMARY KEY (tx_hash, log_index)
+	)`,
+	`CREATE INDEX IF NOT EXISTS swaps_block_number ON swaps (block_number)`,
+	`CREATE TABLE IF NOT EXISTS arbitrages (
+		tx_hash         TEXT NOT NULL,
+		log_index       BIGINT NOT NULL,
+		block_number    BIGINT NOT NULL,
+		arbitrageur     TEXT NOT NULL,
+		token_start     TEXT NOT NULL,
+		token_end       TEXT NOT NULL,
+		amount_in       {{amount}} NOT NULL,
+		amount_out      {{amount}} NOT NULL,
+		profit          {{amount}} NOT NULL,
+		profit_token    TEXT NOT NULL,
+		gas_used        BIGINT NOT NULL,
+		gas_price       {{amount}},
+		net_profit_wei  {{amount}},
+		flash_loan_fees {{amount}},
+		profit_eth_wei  {{amount}},
+		profit_usd      DOUBLE PRECISION,
+		base_fee        {{amount}},
+		burned_fee      {{amount}},
+		priority_fee    {{amount}},
+		blob_fee        {{amount}},
+		PRIMARY KEY (tx_hash, log_index)
+	)`,
+	`CREATE INDEX IF NOT EXISTS arbitrages_block_number ON arbitrages (block_number)`,
+	`CREATE TABLE IF NOT EXISTS arbitrage_legs (
+		tx_hash        TEXT NOT NULL,
+		log_index      BIGINT NOT NULL,
+		leg            INTEGER NOT NULL,
+		swap_log_index BIGINT NOT NULL,
+		PRIMARY KEY (tx_hash, log_index, leg)
+	)`,
+}
+
+// column is a column added to a table after it was first released
+type column struct {
+	table      string
+	name       string
+	definition string
+}
+
+// addedColumns are added to databases created before they existed. New
+// columns must also be part of the CREATE TABLE statement above.
+var addedColumns = []column{
+	{"arbitrages", "profit_eth_wei", "{{amount}}"},
+	{"arbitrages", "profit_usd", "DOUBLE PRECISION"},
+	{"arbitrages", "base_fee", "{{amount}}"},
+	{"arbitrages", "burned_fee", "{{amount}}"},
+	{"arbitrages", "priority_fee", "{{amount}}"},
+	{"arbitrages", "blob_fee", "{{amount}}"},
+}
+
+// schemaFor renders the schema for a driver
+func schemaFor(driver string) []string {
+	amount := amountType(driver)
+	stmts := make([]string, len(schema))
+	for idx, stmt := range schema {
+		stmts[idx] = strings.ReplaceAll(stmt, "{{amount}}", amount)
+	}
+	return stmts
+}
+
+// amountType is the column type of token amounts for a driver
+func amountType(driver string) string {
+	if driver == DriverPostgres {
+		return "NUMERIC(78, 0)"
+	}
+	return "TEXT"
+}
+
+// migrate adds the columns an older database is missing. Neither backend
+// supports ADD COLUMN IF NOT EXISTS in the same way, so each column is probed
+// first.
+func migrate(ctx context.Context, db *sql.DB, driver string) error {
+	amount := amountType(driver)
+	for _, col := range addedColumns {
+		probe := fmt.Sprintf("SELECT %s FROM %s LIMIT 0", col.name, col.table)
+		rows, err := db.QueryContext(ctx, probe)
+		if err == nil {
+			rows.Close()
+			continue
+		}
+
+		definition := strings.ReplaceAll(col.definition, "{{amount}}", amount)
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", col.table, col.name, definition)
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", col.table, col.name, err)
+		}
+	}
+
+	return nil
+}
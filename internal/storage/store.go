@@ -0,0 +1,374 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/devlongs/mev-inspector/internal/config"
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+// Supported database drivers
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// Store persists processed blocks, swaps, arbitrages and pools to a SQL
+// database. All writes are upserts so re-processing a range is safe.
+type Store struct {
+	db     *sql.DB
+	driver string
+}
+
+// Open connects to the configured database and creates the schema
+func Open(ctx context.Context, cfg config.StorageConfig) (*Store, error) {
+	if cfg.Driver != DriverSQLite && cfg.Driver != DriverPostgres {
+		return nil, fmt.Errorf("unsupported storage driver %q", cfg.Driver)
+	}
+
+	// go-sqlite3 registers itself as "sqlite3"
+	driverName := cfg.Driver
+	if driverName == DriverSQLite {
+		driverName = "sqlite3"
+	}
+
+	db, err := sql.Open(driverName, cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %w", cfg.Driver, err)
+	}
+
+	// SQLite allows a single writer; serialize access instead of failing
+	// with SQLITE_BUSY
+	if cfg.Driver == DriverSQLite {
+		db.SetMaxOpenConns(1)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s database: %w", cfg.Driver, err)
+	}
+
+	s := &Store{
+		db:     db,
+		driver: cfg.Driver,
+	}
+
+	for _, stmt := range schemaFor(cfg.Driver) {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to create schema: %w", err)
+		}
+	}
+	if err := migrate(ctx, db, cfg.Driver); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// SaveBlock writes a processed block with its swaps, pools and arbitrages in
+// a single transaction
+func (s *Store) SaveBlock(ctx context.Context, block *types.BlockResult) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, s.rebind(`
+		INSERT INTO blocks (number, hash, parent_hash, swap_count, arbitrage_count, processing_ms)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (number) DO UPDATE SET
+			hash = excluded.hash,
+			parent_hash = excluded.parent_hash,
+			swap_count = excluded.swap_count,
+			arbitrage_count = excluded.arbitrage_count,
+			processing_ms = excluded.processing_ms`),
+		block.Number,
+		block.Hash.Hex(),
+		block.ParentHash.Hex(),
+		len(block.Swaps),
+		len(block.Arbitrages),
+		block.ProcessingTime.Milliseconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save block %d: %w", block.Number, err)
+	}
+
+	for idx := range block.Swaps {
+		if err := s.saveSwap(ctx, tx, &block.Swaps[idx]); err != nil {
+			return err
+		}
+	}
+
+	for idx := range block.Arbitrages {
+		if err := s.saveArbitrage(ctx, tx, &block.Arbitrages[idx]); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit block %d: %w", block.Number, err)
+	}
+
+	return nil
+}
+
+// DeleteFrom removes every block at or above fromBlock along with its swaps
+// and arbitrages, used when those blocks were replaced by a reorg. Pools are
+// kept since they don't depend on a particular chain.
+func (s *Store) DeleteFrom(ctx context.Context, fromBlock uint64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmts := []string{
+		`DELETE FROM arbitrage_legs WHERE tx_hash IN (SELECT tx_hash FROM arbitrages WHERE block_number >= ?)`,
+		`DELETE FROM arbitrages WHERE block_number >= ?`,
+		`DELETE FROM swaps WHERE block_number >= ?`,
+		`DELETE FROM blocks WHERE number >= ?`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, s.rebind(stmt), fromBlock); err != nil {
+			return fmt.Errorf("failed to delete blocks from %d: %w", fromBlock, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit delete: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the database connection
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// saveSwap upserts a swap and the pool it traded on
+func (s *Store) saveSwap(ctx context.Context, tx *sql.Tx, swap *types.Swap) error {
+	_, err := tx.ExecContext(ctx, s.rebind(`
+		INSERT INTO pools (address, pool_id, protocol, token0, token1, first_seen_block)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (address, pool_id) DO UPDATE SET
+			first_seen_block = CASE
+				WHEN excluded.first_seen_block < pools.first_seen_block THEN excluded.first_seen_block
+				ELSE pools.first_seen_block
+			END`),
+		swap.Pool.Hex(),
+		swap.PoolID.Hex(),
+		swap.Protocol,
+		swap.Token0.Hex(),
+		swap.Token1.Hex(),
+		swap.BlockNumber,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save pool %s: %w", swap.Pool.Hex(), err)
+	}
+
+	_, err = tx.ExecContext(ctx, s.rebind(`
+		INSERT INTO swaps (tx_hash, log_index, block_number, protocol, pool, pool_id, sender, recipient,
+			token0, token1, amount0_in, amount1_in, amount0_out, amount1_out)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (tx_hash, log_index) DO UPDATE SET
+			block_number = excluded.block_number,
+			protocol = excluded.protocol,
+			pool = excluded.pool,
+			pool_id = excluded.pool_id,
+			sender = excluded.sender,
+			recipient = excluded.recipient,
+			token0 = excluded.token0,
+			token1 = excluded.token1,
+			amount0_in = excluded.amount0_in,
+			amount1_in = excluded.amount1_in,
+			amount0_out = excluded.amount0_out,
+			amount1_out = excluded.amount1_out`),
+		swap.TxHash.Hex(),
+		swap.LogIndex,
+		swap.BlockNumber,
+		swap.Protocol,
+		swap.Pool.Hex(),
+		swap.PoolID.Hex(),
+		swap.Sender.Hex(),
+		swap.Recipient.Hex(),
+		swap.Token0.Hex(),
+		swap.Token1.Hex(),
+		amount(swap.Amount0In),
+		amount(swap.Amount1In),
+		amount(swap.Amount0Out),
+		amount(swap.Amount1Out),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save swap %s/%d: %w", swap.TxHash.Hex(), swap.LogIndex, err)
+	}
+
+	return nil
+}
+
+// saveArbitrage upserts an arbitrage and replaces its legs. Arbitrages are
+// keyed by transaction and the log index of their first swap.
+func (s *Store) saveArbitrage(ctx context.Context, tx *sql.Tx, arb *types.Arbitrage) error {
+	if len(arb.Path) == 0 {
+		return nil
+	}
+	logIndex := arb.Path[0].LogIndex
+
+	_, err := tx.ExecContext(ctx, s.rebind(`
+		INSERT INTO arbitrages (tx_hash, log_index, block_number, arbitrageur, token_start, token_end,
+			amount_in, amount_out, profit, profit_token, gas_used, gas_price, net_profit_wei, flash_loan_fees,
+			profit_eth_wei, profit_usd, base_fee, burned_fee, priority_fee, blob_fee)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (tx_hash, log_index) DO UPDATE SET
+			block_number = excluded.block_number,
+			arbitrageur = excluded.arbitrageur,
+			token_start = excluded.token_start,
+			token_end = excluded.token_end,
+			amount_in = excluded.amount_in,
+			amount_out = excluded.amount_out,
+			profit = excluded.profit,
+			profit_token = excluded.profit_token,
+			gas_used = excluded.gas_used,
+			gas_price = excluded.gas_price,
+			net_profit_wei = excluded.net_profit_wei,
+			flash_loan_fees = excluded.flash_loan_fees,
+			profit_eth_wei = excluded.profit_eth_wei,
+			profit_usd = excluded.profit_usd,
+			base_fee = excluded.base_fee,
+			burned_fee = excluded.burned_fee,
+			priority_fee = excluded.priority_fee,
+			blob_fee = excluded.blob_fee`),
+		arb.TxHash.Hex(),
+		logIndex,
+		arb.BlockNumber,
+		arb.Arbitrageur.Hex(),
+		arb.TokenStart.Hex(),
+		arb.TokenEnd.Hex(),
+		amount(arb.AmountIn),
+		amount(arb.AmountOut),
+		amount(arb.Profit),
+		arb.ProfitToken.Hex(),
+		arb.GasUsed,
+		nullableAmount(arb.GasPrice),
+		nullableAmount(arb.NetProfitWei),
+		nullableAmount(arb.FlashLoanFees),
+		nullableAmount(arb.ProfitETH),
+		nullableFloat(arb.ProfitUSD),
+		nullableAmount(arb.BaseFee),
+		nullableAmount(arb.BurnedFee),
+		nullableAmount(arb.PriorityFee),
+		nullableAmount(arb.BlobFee),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save arbitrage %s: %w", arb.TxHash.Hex(), err)
+	}
+
+	// Replace the legs so a re-detected path with fewer hops leaves no stale rows
+	_, err = tx.ExecContext(ctx, s.rebind(`DELETE FROM arbitrage_legs WHERE tx_hash = ? AND log_index = ?`),
+		arb.TxHash.Hex(), logIndex)
+	if err != nil {
+		return fmt.Errorf("failed to clear arbitrage legs: %w", err)
+	}
+
+	for leg, swap := range arb.Path {
+		_, err = tx.ExecContext(ctx, s.rebind(`
+			INSERT INTO arbitrage_legs (tx_hash, log_index, leg, swap_log_index)
+			VALUES (?, ?, ?, ?)`),
+			arb.TxHash.Hex(), logIndex, leg, swap.LogIndex,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save arbitrage leg: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// rebind converts ? placeholders to the driver's bind syntax
+func (s *Store) rebind(query string) string {
+	if s.driver != DriverPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// amount formats a token amount as a decimal string, treating nil as zero
+func amount(v *big.Int) string {
+	if v == nil {
+		return "0"
+	}
+	return v.String()
+}
+
+// nullableAmount formats an optional amount, storing nil as NULL
+func nullableAmount(v *big.Int) sql.NullString {
+	if v == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: v.String(), Valid: true}
+}
+
+// nullableFloat stores nil as NULL
+func nullableFloat(v *float64) sql.NullFloat64 {
+	if v == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *v, Valid: true}
+}
+
+// Name returns the sink name
+func (s *Store) Name() string {
+	return "database"
+}
+
+// Durable implements output.Durable: every block must reach the database
+func (s *Store) Durable() bool {
+	return true
+}
+
+// OnSwap implements output.Sink; swaps are saved with their block
+func (s *Store) OnSwap(swap *types.Swap) error {
+	return nil
+}
+
+// OnArbitrage implements output.Sink; arbitrages are saved with their block
+func (s *Store) OnArbitrage(arb *types.Arbitrage) error {
+	return nil
+}
+
+// OnBlock saves a processed block
+func (s *Store) OnBlock(block *types.BlockResult) error {
+	return s.SaveBlock(context.Background(), block)
+}
+
+// OnReorg deletes the results of blocks replaced by a reorg
+func (s *Store) OnReorg(ancestor, tip uint64, retracted []*types.BlockResult) error {
+	return s.DeleteFrom(context.Background(), ancestor+1)
+}
+
+// OnError implements output.Sink
+func (s *Store) OnError(err error, errContext string) error {
+	return nil
+}
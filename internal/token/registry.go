@@ -0,0 +1,353 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog/log"
+
+	"github.com/devlongs/mev-inspector/internal/eth"
+	"github.com/devlongs/mev-inspector/internal/metrics"
+	"github.com/devlongs/mev-inspector/pkg/types"
+)
+
+// ERC20 metadata function selectors
+var (
+	symbolSelector   = common.Hex2Bytes("95d89b41") // symbol()
+	nameSelector     = common.Hex2Bytes("06fdde03") // name()
+	decimalsSelector = common.Hex2Bytes("313ce567") // decimals()
+)
+
+// NativeETH is the placeholder address some DEXes (e.g. Curve) use for ETH
+var NativeETH = common.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE")
+
+// Longest symbol or name kept; anything longer is almost certainly spam
+const maxTextLength = 64
+
+// entry is the cached metadata of one token
+type entry struct {
+	Symbol   string `json:"symbol"`
+	Name     string `json:"name,omitempty"`
+	Decimals *uint8 `json:"decimals"` // Nil when decimals() reverted
+}
+
+// cacheFile is the on-disk cache format
+type cacheFile struct {
+	ChainID uint64                    `json:"chain_id"`
+	Tokens  map[common.Address]*entry `json:"tokens"`
+}
+
+// Registry resolves ERC20 symbols, names and decimals and caches them in
+// memory and, optionally, on disk. A nil Registry resolves nothing and
+// renders addresses and raw amounts.
+type Registry struct {
+	client  eth.Reader
+	path    string
+	chainID uint64
+	tokens  map[common.Address]*entry
+	mu      sync.RWMutex
+	saveMu  sync.Mutex // Keeps an older snapshot from replacing a newer one
+}
+
+// NewRegistry creates a token registry, loading the cache at path if there is
+// one. An empty path keeps the cache in memory only.
+func NewRegistry(client eth.Reader, path string) (*Registry, error) {
+	eighteen := uint8(18)
+	r := &Registry{
+		client:  client,
+		path:    path,
+		chainID: client.ChainID().Uint64(),
+		tokens: map[common.Address]*entry{
+			NativeETH: {Symbol: "ETH", Name: "Ether", Decimals: &eighteen},
+		},
+	}
+
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token cache: %w", err)
+	}
+
+	var cache cacheFile
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("failed to parse token cache %s: %w", path, err)
+	}
+	if cache.ChainID != r.chainID {
+		log.Warn().
+			Str("path", path).
+			Uint64("cacheChainID", cache.ChainID).
+			Uint64("chainID", r.chainID).
+			Msg("Token cache is for another chain, ignoring it")
+		return r, nil
+	}
+
+	for addr, e := range cache.Tokens {
+		r.tokens[addr] = e
+	}
+	log.Info().Int("tokens", len(cache.Tokens)).Str("path", path).Msg("Loaded token cache")
+
+	return r, nil
+}
+
+// Resolve fetches the metadata of every token that isn't cached yet in a
+// single multicall and saves the cache. Tokens whose calls return nothing or
+// revert are cached as such so they aren't queried again; tokens with a call
+// that failed for another reason, such as a rate limit, are left uncached
+// and queried again next time.
+func (r *Registry) Resolve(ctx context.Context, tokens []common.Address) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.RLock()
+	var missing []common.Address
+	seen := make(map[common.Address]bool, len(tokens))
+	for _, addr := range tokens {
+		if seen[addr] || addr == (common.Address{}) {
+			continue
+		}
+		seen[addr] = true
+
+		_, ok := r.tokens[addr]
+		metrics.TokenCacheLookup(ok)
+		if !ok {
+			missing = append(missing, addr)
+		}
+	}
+	r.mu.RUnlock()
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	calls := make([]eth.Call, 0, 3*len(missing))
+	for _, addr := range missing {
+		calls = append(calls,
+			eth.Call{Target: addr, Data: symbolSelector},
+			eth.Call{Target: addr, Data: decimalsSelector},
+			eth.Call{Target: addr, Data: nameSelector},
+		)
+	}
+
+	results, err := r.client.Multicall(ctx, calls, nil)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %d tokens: %w", len(missing), err)
+	}
+
+	var retry int
+	r.mu.Lock()
+	for idx, addr := range missing {
+		if !settled(results[3*idx : 3*idx+3]) {
+			retry++
+			continue
+		}
+
+		e := &entry{}
+		if res := results[3*idx]; res.Err == nil {
+			e.Symbol = decodeText(res.Data)
+		}
+		if res := results[3*idx+1]; res.Err == nil {
+			e.Decimals = decodeDecimals(res.Data)
+		}
+		if res := results[3*idx+2]; res.Err == nil {
+			e.Name = decodeText(res.Data)
+		}
+		r.tokens[addr] = e
+	}
+	r.mu.Unlock()
+
+	log.Debug().Int("tokens", len(missing)-retry).Int("retry", retry).Msg("Resolved token metadata")
+
+	return r.save()
+}
+
+// settled reports whether every call of a token either returned or
+// reverted, so its result won't change by asking again
+func settled(results []eth.CallResult) bool {
+	for _, res := range results {
+		if res.Err != nil && !eth.IsRevertError(res.Err) {
+			return false
+		}
+	}
+	return true
+}
+
+// Lookup returns the metadata of a resolved token. ok is false for tokens
+// that haven't been resolved or have no decimals() function.
+func (r *Registry) Lookup(addr common.Address) (types.Token, bool) {
+	if r == nil {
+		return types.Token{Address: addr}, false
+	}
+
+	r.mu.RLock()
+	e, ok := r.tokens[addr]
+	r.mu.RUnlock()
+
+	if !ok || e.Decimals == nil {
+		return types.Token{Address: addr}, false
+	}
+	return types.Token{
+		Address:  addr,
+		Symbol:   e.Symbol,
+		Name:     e.Name,
+		Decimals: *e.Decimals,
+	}, true
+}
+
+// Symbol returns the symbol of a token, or its shortened address if the
+// symbol is unknown
+func (r *Registry) Symbol(addr common.Address) string {
+	if r != nil {
+		r.mu.RLock()
+		e, ok := r.tokens[addr]
+		r.mu.RUnlock()
+		if ok && e.Symbol != "" {
+			return e.Symbol
+		}
+	}
+	return addr.Hex()[:10]
+}
+
+// FormatAmount renders an amount of a token in whole units followed by its
+// symbol, e.g. "1.5 WETH". Amounts of tokens without known decimals are
+// rendered raw.
+func (r *Registry) FormatAmount(addr common.Address, amount *big.Int) string {
+	if amount == nil {
+		return "N/A"
+	}
+	if token, ok := r.Lookup(addr); ok {
+		return FormatUnits(amount, token.Decimals) + " " + r.Symbol(addr)
+	}
+	return amount.String() + " " + r.Symbol(addr)
+}
+
+// FormatUnits renders an integer amount with the given number of decimals,
+// without trailing zeros
+func FormatUnits(amount *big.Int, decimals uint8) string {
+	digits := new(big.Int).Abs(amount).String()
+	sign := ""
+	if amount.Sign() < 0 {
+		sign = "-"
+	}
+	if decimals == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= int(decimals) {
+		digits = strings.Repeat("0", int(decimals)-len(digits)+1) + digits
+	}
+	whole := digits[:len(digits)-int(decimals)]
+	frac := strings.TrimRight(digits[len(digits)-int(decimals):], "0")
+	if frac == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + frac
+}
+
+// save writes the cache to a temporary file and renames it over the previous
+// one so a crash never leaves a partial file
+func (r *Registry) save() error {
+	if r.path == "" {
+		return nil
+	}
+
+	r.saveMu.Lock()
+	defer r.saveMu.Unlock()
+
+	r.mu.RLock()
+	data, err := json.MarshalIndent(cacheFile{ChainID: r.chainID, Tokens: r.tokens}, "", "  ")
+	r.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(r.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create token cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(r.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create token cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write token cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close token cache: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("failed to replace token cache: %w", err)
+	}
+
+	return nil
+}
+
+// decodeText decodes the result of symbol() or name(), which is an ABI
+// string for most tokens and bytes32 for some older ones such as MKR
+func decodeText(data []byte) string {
+	var raw []byte
+	switch {
+	case len(data) == 32:
+		raw = data
+	case len(data) >= 64:
+		// Compare against what's left of data rather than adding to the
+		// offset and length, which are untrusted and could overflow
+		offset := new(big.Int).SetBytes(data[:32])
+		if !offset.IsUint64() || offset.Uint64() > uint64(len(data)-32) {
+			return ""
+		}
+		start := offset.Uint64() + 32
+		length := new(big.Int).SetBytes(data[start-32 : start])
+		if !length.IsUint64() || length.Uint64() > uint64(len(data))-start {
+			return ""
+		}
+		raw = data[start : start+length.Uint64()]
+	default:
+		return ""
+	}
+
+	text := strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r == unicode.ReplacementChar || !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, string(raw)))
+
+	if runes := []rune(text); len(runes) > maxTextLength {
+		text = string(runes[:maxTextLength])
+	}
+	return text
+}
+
+// decodeDecimals decodes the result of decimals(), which must fit in a uint8
+func decodeDecimals(data []byte) *uint8 {
+	if len(data) < 32 {
+		return nil
+	}
+	value := new(big.Int).SetBytes(data[:32])
+	if !value.IsUint64() || value.Uint64() > 255 {
+		return nil
+	}
+	decimals := uint8(value.Uint64())
+	return &decimals
+}
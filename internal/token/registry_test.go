@@ -0,0 +1,160 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/devlongs/mev-inspector/internal/eth"
+)
+
+// fakeReader answers token multicalls from per-token results
+type fakeReader struct {
+	eth.Reader
+	results map[common.Address][]eth.CallResult // symbol, decimals, name
+	queried []common.Address
+}
+
+func (f *fakeReader) ChainID() *big.Int { return big.NewInt(1) }
+
+func (f *fakeReader) Multicall(_ context.Context, calls []eth.Call, _ *big.Int) ([]eth.CallResult, error) {
+	var results []eth.CallResult
+	for idx := 0; idx < len(calls); idx += 3 {
+		f.queried = append(f.queried, calls[idx].Target)
+		results = append(results, f.results[calls[idx].Target]...)
+	}
+	return results, nil
+}
+
+func TestResolveCachesOnlySettledResults(t *testing.T) {
+	weth := common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	limited := common.HexToAddress("0x0000000000000000000000000000000000001111")
+	reverting := common.HexToAddress("0x0000000000000000000000000000000000002222")
+
+	symbol := common.Hex2Bytes("4d4b520000000000000000000000000000000000000000000000000000000000")
+	decimals := common.LeftPadBytes([]byte{18}, 32)
+	rateLimit := errors.New("429 Too Many Requests")
+	reverted := errors.New("execution reverted")
+
+	reader := &fakeReader{results: map[common.Address][]eth.CallResult{
+		weth:      {{Data: symbol}, {Data: decimals}, {Data: symbol}},
+		limited:   {{Data: symbol}, {Err: rateLimit}, {Data: symbol}},
+		reverting: {{Err: reverted}, {Err: reverted}, {Err: reverted}},
+	}}
+	registry, err := NewRegistry(reader, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := []common.Address{weth, limited, reverting}
+	if err := registry.Resolve(context.Background(), tokens); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := registry.Lookup(weth); !ok {
+		t.Error("token with results was not cached")
+	}
+	if _, ok := registry.Lookup(reverting); ok {
+		t.Error("token without decimals() has metadata")
+	}
+
+	// Only the rate-limited token is asked again
+	reader.queried = nil
+	reader.results[limited] = []eth.CallResult{{Data: symbol}, {Data: decimals}, {Data: symbol}}
+	if err := registry.Resolve(context.Background(), tokens); err != nil {
+		t.Fatal(err)
+	}
+	if len(reader.queried) != 1 || reader.queried[0] != limited {
+		t.Fatalf("queried %v, want only %s", reader.queried, limited.Hex())
+	}
+	if _, ok := registry.Lookup(limited); !ok {
+		t.Error("rate-limited token was not resolved on retry")
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "abi string",
+			data: "0000000000000000000000000000000000000000000000000000000000000020" +
+				"0000000000000000000000000000000000000000000000000000000000000004" +
+				"5745544800000000000000000000000000000000000000000000000000000000",
+			want: "WETH",
+		},
+		{
+			name: "bytes32",
+			data: "4d4b520000000000000000000000000000000000000000000000000000000000",
+			want: "MKR",
+		},
+		{
+			name: "out of range offset",
+			data: "00000000000000000000000000000000000000000000000000000000000000ff" +
+				"0000000000000000000000000000000000000000000000000000000000000004",
+			want: "",
+		},
+		{
+			name: "overflowing offset",
+			data: "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff" +
+				"0000000000000000000000000000000000000000000000000000000000000004",
+			want: "",
+		},
+		{
+			name: "offset near max uint64",
+			data: "000000000000000000000000000000000000000000000000ffffffffffffffe0" +
+				"0000000000000000000000000000000000000000000000000000000000000004",
+			want: "",
+		},
+		{
+			name: "out of range length",
+			data: "0000000000000000000000000000000000000000000000000000000000000020" +
+				"0000000000000000000000000000000000000000000000000000000000000040" +
+				"5745544800000000000000000000000000000000000000000000000000000000",
+			want: "",
+		},
+		{
+			name: "length near max uint64",
+			data: "0000000000000000000000000000000000000000000000000000000000000020" +
+				"000000000000000000000000000000000000000000000000ffffffffffffffff" +
+				"5745544800000000000000000000000000000000000000000000000000000000",
+			want: "",
+		},
+		{
+			name: "empty",
+			data: "",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := decodeText(common.Hex2Bytes(tt.data)); got != tt.want {
+			t.Errorf("%s: decodeText() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormatUnits(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals uint8
+		want     string
+	}{
+		{"1500000000000000000", 18, "1.5"},
+		{"1000000", 6, "1"},
+		{"1", 6, "0.000001"},
+		{"-2500000", 6, "-2.5"},
+		{"42", 0, "42"},
+	}
+
+	for _, tt := range tests {
+		amount, _ := new(big.Int).SetString(tt.amount, 10)
+		if got := FormatUnits(amount, tt.decimals); got != tt.want {
+			t.Errorf("FormatUnits(%s, %d) = %q, want %q", tt.amount, tt.decimals, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -10,6 +11,7 @@ import (
 type Token struct {
 	Address  common.Address
 	Symbol   string
+	Name     string
 	Decimals uint8
 }
 
@@ -28,6 +30,7 @@ type Swap struct {
 	BlockNumber uint64
 	LogIndex    uint
 	Pool        common.Address
+	PoolID      common.Hash // Pool identifier for singleton DEXes (Balancer, Uniswap V4)
 	Protocol    string
 	Sender      common.Address
 	Recipient   common.Address
@@ -41,6 +44,8 @@ type Swap struct {
 	SqrtPriceX96 *big.Int
 	Liquidity    *big.Int
 	Tick         *big.Int
+	// V4 specific
+	Hooks common.Address
 }
 
 // SwapPath represents a sequence of swaps in a single transaction
@@ -50,6 +55,7 @@ type SwapPath struct {
 
 // Arbitrage represents a detected arbitrage opportunity
 type Arbitrage struct {
+	Type         ArbitrageType
 	TxHash       common.Hash
 	BlockNumber  uint64
 	Arbitrageur  common.Address
@@ -61,14 +67,86 @@ type Arbitrage struct {
 	Profit       *big.Int
 	ProfitToken  common.Address
 	GasUsed      uint64
-	GasPrice     *big.Int
-	NetProfitWei *big.Int
+	GasPrice     *big.Int // Effective price paid per gas
+	NetProfitWei *big.Int // Net profit in ETH; nil when the profit token couldn't be priced
+	// Gas cost split into the base fee burned and the priority fee paid to
+	// the proposer; nil when the block's base fee couldn't be fetched.
+	// BaseFee is nil for blocks before London.
+	BaseFee     *big.Int
+	BurnedFee   *big.Int
+	PriorityFee *big.Int
+	BlobFee     *big.Int // Blob gas cost of type-3 transactions, burned; nil otherwise
+	// Profit valued at the prices of the arbitrage's block; nil when no price
+	// was found
+	ProfitETH *big.Int // In wei
+	ProfitUSD *float64
+	// Flash loans funding the arbitrage and their total fees in ProfitToken,
+	// deducted from NetProfitWei. Fees in other tokens are converted at the
+	// block's prices; FlashLoanFees is nil when one of them can't be.
+	FlashLoans    []FlashLoan
+	FlashLoanFees *big.Int
+}
+
+// FlashLoan represents capital borrowed and repaid within a single transaction
+type FlashLoan struct {
+	Protocol string // "aave_v2", "aave_v3", "balancer_v2" or "uniswap_v3"
+	Lender   common.Address
+	Borrower common.Address
+	Token    common.Address
+	Amount   *big.Int
+	Fee      *big.Int
+}
+
+// Sandwich represents a detected sandwich attack: an attacker frontruns one
+// or more victim swaps on a pool and backruns them in the same block
+type Sandwich struct {
+	BlockNumber uint64
+	Attacker    common.Address
+	Pool        common.Address
+	PoolID      common.Hash // Pool identifier for singleton DEXes (Balancer, Uniswap V4)
+	Frontrun    Swap
+	Victims     []Swap
+	Backrun     Swap
+	ProfitToken common.Address
+	Profit      *big.Int // Backrun output minus frontrun input, in ProfitToken
+	VictimLoss  *big.Int // Value extracted from victims, in ProfitToken; nil when unknown
+}
+
+// Liquidation represents a lending protocol liquidation and any swaps the
+// liquidator made in the same transaction
+type Liquidation struct {
+	TxHash           common.Hash
+	BlockNumber      uint64
+	LogIndex         uint
+	Protocol         string // "aave_v2", "aave_v3", "compound_v2" or "compound_v3"
+	Liquidator       common.Address
+	Borrower         common.Address
+	Absorbed         bool // The protocol took the collateral and debt itself (Compound V3 absorb)
+	CollateralAsset  common.Address
+	CollateralAmount *big.Int // Collateral received by the liquidator, or absorbed by the protocol, in CollateralAsset units
+	DebtAsset        common.Address
+	DebtRepaid       *big.Int // Debt repaid by the liquidator, in DebtAsset units
+	Swaps            []Swap
+	Profit           *big.Int // Nil when the collateral was not converted back to DebtAsset
+	ProfitToken      common.Address
+}
+
+// BlockResult holds everything detected in a single block
+type BlockResult struct {
+	Number         uint64
+	Hash           common.Hash
+	ParentHash     common.Hash
+	Swaps          []Swap
+	Arbitrages     []Arbitrage
+	Sandwiches     []Sandwich
+	Liquidations   []Liquidation
+	ProcessingTime time.Duration // Wall time from the first of this block's transactions starting to the last finishing
 }
 
 // ArbitrageType indicates the type of arbitrage detected
 type ArbitrageType string
 
 const (
-	ArbitrageTypeCyclic   ArbitrageType = "cyclic"   // A -> B -> C -> A
+	ArbitrageTypeCyclic   ArbitrageType = "cyclic"    // A -> B -> C -> A
 	ArbitrageTypeCrossDEX ArbitrageType = "cross_dex" // Same pair, different pools
 )